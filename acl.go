@@ -0,0 +1,109 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+)
+
+type Permission string
+
+const (
+	PermissionRead        Permission = "READ"
+	PermissionWrite       Permission = "WRITE"
+	PermissionReadACP     Permission = "READ_ACP"
+	PermissionWriteACP    Permission = "WRITE_ACP"
+	PermissionFullControl Permission = "FULL_CONTROL"
+)
+
+// Grantee types
+const (
+	GranteeCanonicalUser         = "CanonicalUser"
+	GranteeAmazonCustomerByEmail = "AmazonCustomerByEmail"
+	GranteeGroup                 = "Group"
+)
+
+// Predefined group URIs
+const (
+	GranteeAllUsers           = "http://acs.amazonaws.com/groups/global/AllUsers"
+	GranteeAuthenticatedUsers = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+	GranteeLogDelivery        = "http://acs.amazonaws.com/groups/s3/LogDelivery"
+)
+
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// AccessControlPolicy is the access control list of an object
+type AccessControlPolicy struct {
+	XMLName xml.Name `xml:"AccessControlPolicy"`
+	Owner   Owner
+	Grants  []Grant `xml:"AccessControlList>Grant"`
+}
+
+type Owner struct {
+	ID          string
+	DisplayName string `xml:",omitempty"`
+}
+
+type Grant struct {
+	Grantee    Grantee
+	Permission Permission
+}
+
+// Grantee identifies who a grant applies to. Depending on Type, either ID,
+// EmailAddress or URI must be set.
+type Grantee struct {
+	Type         string `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr"`
+	ID           string `xml:",omitempty"`
+	DisplayName  string `xml:",omitempty"`
+	EmailAddress string `xml:",omitempty"`
+	URI          string `xml:",omitempty"`
+}
+
+// MarshalXML writes the xsi:type attribute with the literal prefix S3 expects
+func (g Grantee) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = append(start.Attr,
+		xml.Attr{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNamespace},
+		xml.Attr{Name: xml.Name{Local: "xsi:type"}, Value: g.Type},
+	)
+	return e.EncodeElement(struct {
+		ID           string `xml:",omitempty"`
+		DisplayName  string `xml:",omitempty"`
+		EmailAddress string `xml:",omitempty"`
+		URI          string `xml:",omitempty"`
+	}{g.ID, g.DisplayName, g.EmailAddress, g.URI}, start)
+}
+
+func (o *object) GetACL() (*AccessControlPolicy, error) {
+	req, err := http.NewRequest("GET", o.url("?acl"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.s3.do(req, 200, "error getting acl")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	acp := new(AccessControlPolicy)
+	if err := xml.NewDecoder(resp.Body).Decode(acp); err != nil {
+		return nil, err
+	}
+	return acp, nil
+}
+
+func (o *object) PutACL(acp *AccessControlPolicy) error {
+	b, err := xml.Marshal(acp)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("PUT", o.url("?acl"), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	resp, err := o.s3.do(req, 200, "error putting acl")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
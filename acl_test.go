@@ -0,0 +1,65 @@
+package s3
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+const aclXML = `<?xml version="1.0" encoding="UTF-8"?>
+<AccessControlPolicy xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Owner>
+    <ID>owner-id</ID>
+    <DisplayName>owner</DisplayName>
+  </Owner>
+  <AccessControlList>
+    <Grant>
+      <Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="CanonicalUser">
+        <ID>owner-id</ID>
+        <DisplayName>owner</DisplayName>
+      </Grantee>
+      <Permission>FULL_CONTROL</Permission>
+    </Grant>
+    <Grant>
+      <Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="Group">
+        <URI>http://acs.amazonaws.com/groups/global/AllUsers</URI>
+      </Grantee>
+      <Permission>READ</Permission>
+    </Grant>
+  </AccessControlList>
+</AccessControlPolicy>`
+
+func TestAccessControlPolicy(t *testing.T) {
+	var acp AccessControlPolicy
+	if err := xml.Unmarshal([]byte(aclXML), &acp); err != nil {
+		t.Fatal(err)
+	}
+	if x := acp.Owner.ID; x != "owner-id" {
+		t.Fatal(x)
+	}
+	if x := len(acp.Grants); x != 2 {
+		t.Fatal(x)
+	}
+	if g := acp.Grants[0]; g.Grantee.Type != GranteeCanonicalUser || g.Grantee.ID != "owner-id" || g.Permission != PermissionFullControl {
+		t.Fatal(g)
+	}
+	if g := acp.Grants[1]; g.Grantee.Type != GranteeGroup || g.Grantee.URI != GranteeAllUsers || g.Permission != PermissionRead {
+		t.Fatal(g)
+	}
+
+	// marshal and parse again
+	b, err := xml.Marshal(&acp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `xsi:type="Group"`) {
+		t.Fatal(string(b))
+	}
+	var acp2 AccessControlPolicy
+	if err := xml.Unmarshal(b, &acp2); err != nil {
+		t.Fatal(err)
+	}
+	if x := acp2.Grants[1].Grantee; x != acp.Grants[1].Grantee {
+		t.Fatal(x)
+	}
+}
@@ -0,0 +1,176 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"time"
+)
+
+// Bucket is a bucket of the account
+type Bucket struct {
+	Name         string
+	CreationDate time.Time
+}
+
+// ObjectOwnership controls who owns objects uploaded by other accounts and
+// whether ACLs are used.
+type ObjectOwnership string
+
+const (
+	// BucketOwnerEnforced disables ACLs, the bucket owner owns all objects
+	BucketOwnerEnforced ObjectOwnership = "BucketOwnerEnforced"
+
+	// BucketOwnerPreferred makes the bucket owner own objects uploaded with
+	// the bucket-owner-full-control ACL
+	BucketOwnerPreferred ObjectOwnership = "BucketOwnerPreferred"
+
+	// ObjectWriter makes the uploading account own the object
+	ObjectWriter ObjectOwnership = "ObjectWriter"
+)
+
+// CreateBucketOptions configure a new bucket
+type CreateBucketOptions struct {
+	// LocationConstraint is the region to create the bucket in. Empty
+	// creates the bucket in us-east-1.
+	LocationConstraint string
+
+	ACL             ACL
+	ObjectOwnership ObjectOwnership
+
+	// ObjectLock enables object lock, which also enables versioning
+	ObjectLock bool
+}
+
+// ListBuckets returns all buckets of the account. The configured bucket is
+// ignored, see also Client.ListBuckets.
+func (s3 *S3) ListBuckets() ([]Bucket, error) {
+	req, err := http.NewRequest("GET", s3.client().endpoint()+`/`, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s3.do(req, 200, "error listing buckets")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		Buckets struct {
+			Bucket []Bucket
+		}
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return res.Buckets.Bucket, nil
+}
+
+// CreateBucket creates the configured bucket. An existing bucket fails with the
+// ErrorCode BucketAlreadyOwnedByYou, or BucketAlreadyExists if another account
+// owns it.
+func (s3 *S3) CreateBucket(opts ...CreateBucketOptions) error {
+	var co CreateBucketOptions
+	if len(opts) > 0 {
+		co = opts[0]
+	}
+
+	var body []byte
+	if co.LocationConstraint != "" && co.LocationConstraint != "us-east-1" {
+		v := struct {
+			XMLName            xml.Name `xml:"CreateBucketConfiguration"`
+			Xmlns              string   `xml:"xmlns,attr"`
+			LocationConstraint string
+		}{
+			Xmlns:              "http://s3.amazonaws.com/doc/2006-03-01/",
+			LocationConstraint: co.LocationConstraint,
+		}
+		b, err := xml.Marshal(&v)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+
+	req, err := http.NewRequest("PUT", s3.bucketURL(""), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if co.ACL != "" {
+		req.Header.Set("x-amz-acl", string(co.ACL))
+	}
+	if co.ObjectOwnership != "" {
+		req.Header.Set("x-amz-object-ownership", string(co.ObjectOwnership))
+	}
+	if co.ObjectLock {
+		req.Header.Set("x-amz-bucket-object-lock-enabled", "true")
+	}
+
+	resp, err := s3.do(req, 200, "error creating bucket")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DeleteBucket deletes the configured bucket, which must be empty. A bucket
+// that isn't empty fails with the ErrorCode BucketNotEmpty.
+func (s3 *S3) DeleteBucket() error {
+	req, err := http.NewRequest("DELETE", s3.bucketURL(""), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s3.do(req, 204, "error deleting bucket")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// HeadBucket checks that the configured bucket exists and is accessible, and
+// returns the response header.
+func (s3 *S3) HeadBucket() (Header, error) {
+	req, err := http.NewRequest("HEAD", s3.bucketURL(""), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s3.do(req, 200, "error getting bucket head")
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return Header(resp.Header), nil
+}
+
+// GetBucketLocation returns the region of the configured bucket
+func (s3 *S3) GetBucketLocation() (string, error) {
+	req, err := http.NewRequest("GET", s3.bucketURL("?location"), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s3.do(req, 200, "error getting bucket location")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var loc string
+	if err := xml.NewDecoder(resp.Body).Decode(&loc); err != nil {
+		return "", err
+	}
+	return locationRegion(loc), nil
+}
+
+// locationRegion turns a location constraint into a region. S3 reports
+// us-east-1 as an empty constraint and eu-west-1 as EU for old buckets.
+func locationRegion(loc string) string {
+	switch loc {
+	case "":
+		return "us-east-1"
+	case "EU":
+		return "eu-west-1"
+	}
+	return loc
+}
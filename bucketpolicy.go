@@ -0,0 +1,394 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// PolicyVersion is the current version of the IAM policy language
+const PolicyVersion = "2012-10-17"
+
+// Effect tells whether a statement allows or denies
+type Effect string
+
+const (
+	PolicyAllow Effect = "Allow"
+	PolicyDeny  Effect = "Deny"
+)
+
+// ConditionOperator compares a condition key of the request with the values of
+// a condition. Operators may be qualified with ForAllValues: or ForAnyValue:
+// and take an IfExists suffix.
+type ConditionOperator string
+
+const (
+	ConditionStringEquals              ConditionOperator = "StringEquals"
+	ConditionStringNotEquals           ConditionOperator = "StringNotEquals"
+	ConditionStringEqualsIgnoreCase    ConditionOperator = "StringEqualsIgnoreCase"
+	ConditionStringNotEqualsIgnoreCase ConditionOperator = "StringNotEqualsIgnoreCase"
+	ConditionStringLike                ConditionOperator = "StringLike"
+	ConditionStringNotLike             ConditionOperator = "StringNotLike"
+	ConditionNumericEquals             ConditionOperator = "NumericEquals"
+	ConditionNumericNotEquals          ConditionOperator = "NumericNotEquals"
+	ConditionNumericLessThan           ConditionOperator = "NumericLessThan"
+	ConditionNumericLessThanEquals     ConditionOperator = "NumericLessThanEquals"
+	ConditionNumericGreaterThan        ConditionOperator = "NumericGreaterThan"
+	ConditionNumericGreaterThanEquals  ConditionOperator = "NumericGreaterThanEquals"
+	ConditionDateEquals                ConditionOperator = "DateEquals"
+	ConditionDateNotEquals             ConditionOperator = "DateNotEquals"
+	ConditionDateLessThan              ConditionOperator = "DateLessThan"
+	ConditionDateLessThanEquals        ConditionOperator = "DateLessThanEquals"
+	ConditionDateGreaterThan           ConditionOperator = "DateGreaterThan"
+	ConditionDateGreaterThanEquals     ConditionOperator = "DateGreaterThanEquals"
+	ConditionBool                      ConditionOperator = "Bool"
+	ConditionBinaryEquals              ConditionOperator = "BinaryEquals"
+	ConditionIPAddress                 ConditionOperator = "IpAddress"
+	ConditionNotIPAddress              ConditionOperator = "NotIpAddress"
+	ConditionArnEquals                 ConditionOperator = "ArnEquals"
+	ConditionArnNotEquals              ConditionOperator = "ArnNotEquals"
+	ConditionArnLike                   ConditionOperator = "ArnLike"
+	ConditionArnNotLike                ConditionOperator = "ArnNotLike"
+	ConditionNull                      ConditionOperator = "Null"
+)
+
+var conditionOperators = map[ConditionOperator]bool{
+	ConditionStringEquals: true, ConditionStringNotEquals: true,
+	ConditionStringEqualsIgnoreCase: true, ConditionStringNotEqualsIgnoreCase: true,
+	ConditionStringLike: true, ConditionStringNotLike: true,
+	ConditionNumericEquals: true, ConditionNumericNotEquals: true,
+	ConditionNumericLessThan: true, ConditionNumericLessThanEquals: true,
+	ConditionNumericGreaterThan: true, ConditionNumericGreaterThanEquals: true,
+	ConditionDateEquals: true, ConditionDateNotEquals: true,
+	ConditionDateLessThan: true, ConditionDateLessThanEquals: true,
+	ConditionDateGreaterThan: true, ConditionDateGreaterThanEquals: true,
+	ConditionBool: true, ConditionBinaryEquals: true,
+	ConditionIPAddress: true, ConditionNotIPAddress: true,
+	ConditionArnEquals: true, ConditionArnNotEquals: true,
+	ConditionArnLike: true, ConditionArnNotLike: true,
+	ConditionNull: true,
+}
+
+// Conditions map operators to condition keys, e.g. aws:SecureTransport, and
+// the values compared with them.
+type Conditions map[ConditionOperator]map[string]StringList
+
+// BucketPolicy is an IAM policy document attached to a bucket
+//
+//	b := &s3.S3{Bucket: "bucket", AccessKey: key, Secret: secret}
+//	p := s3.NewBucketPolicy()
+//	p.Allow("s3:GetObject").For(s3.Everyone()).On(b.ObjectARN("public/*"))
+//	p.Deny("s3:*").For(s3.Everyone()).On(b.BucketARN(), b.ObjectARN("*")).
+//		When(s3.ConditionBool, "aws:SecureTransport", "false")
+//	err := b.PutBucketPolicy(p)
+type BucketPolicy struct {
+	Version    string       `json:"Version"`
+	ID         string       `json:"Id,omitempty"`
+	Statements []*Statement `json:"Statement"`
+}
+
+// Statement allows or denies actions on resources to principals. Only one of
+// each pair of a field and its Not field may be set.
+type Statement struct {
+	Sid          string     `json:"Sid,omitempty"`
+	Effect       Effect     `json:"Effect"`
+	Principal    *Principal `json:"Principal,omitempty"`
+	NotPrincipal *Principal `json:"NotPrincipal,omitempty"`
+	Actions      StringList `json:"Action,omitempty"`
+	NotActions   StringList `json:"NotAction,omitempty"`
+	Resources    StringList `json:"Resource,omitempty"`
+	NotResources StringList `json:"NotResource,omitempty"`
+	Conditions   Conditions `json:"Condition,omitempty"`
+}
+
+// Principal is the account, user, role or service a statement applies to, or
+// everyone if Everyone is set.
+type Principal struct {
+	Everyone      bool
+	AWS           StringList
+	Service       StringList
+	CanonicalUser StringList
+	Federated     StringList
+}
+
+// NewBucketPolicy returns a policy of the current version
+func NewBucketPolicy() *BucketPolicy {
+	return &BucketPolicy{Version: PolicyVersion}
+}
+
+// Allow adds a statement allowing the actions
+func (p *BucketPolicy) Allow(actions ...string) *Statement {
+	return p.add(PolicyAllow, actions)
+}
+
+// Deny adds a statement denying the actions
+func (p *BucketPolicy) Deny(actions ...string) *Statement {
+	return p.add(PolicyDeny, actions)
+}
+
+func (p *BucketPolicy) add(effect Effect, actions []string) *Statement {
+	s := &Statement{Effect: effect, Actions: actions}
+	p.Statements = append(p.Statements, s)
+	return s
+}
+
+// For sets the principal
+func (s *Statement) For(p *Principal) *Statement {
+	s.Principal = p
+	return s
+}
+
+// On adds resources, see S3.BucketARN and S3.ObjectARN
+func (s *Statement) On(resources ...string) *Statement {
+	s.Resources = append(s.Resources, resources...)
+	return s
+}
+
+// When adds a condition
+func (s *Statement) When(op ConditionOperator, key string, values ...string) *Statement {
+	if s.Conditions == nil {
+		s.Conditions = make(Conditions)
+	}
+	if s.Conditions[op] == nil {
+		s.Conditions[op] = make(map[string]StringList)
+	}
+	s.Conditions[op][key] = append(s.Conditions[op][key], values...)
+	return s
+}
+
+// Everyone returns the anonymous principal *
+func Everyone() *Principal {
+	return &Principal{Everyone: true}
+}
+
+// AWSPrincipal returns a principal of AWS account ids or user and role ARNs
+func AWSPrincipal(arns ...string) *Principal {
+	return &Principal{AWS: arns}
+}
+
+// ServicePrincipal returns a principal of AWS services, e.g.
+// logging.s3.amazonaws.com
+func ServicePrincipal(services ...string) *Principal {
+	return &Principal{Service: services}
+}
+
+// BucketARN returns the ARN of the bucket
+func (s3 *S3) BucketARN() string {
+	return "arn:aws:s3:::" + s3.Bucket
+}
+
+// ObjectARN returns the ARN of the keys matching the pattern, which is relative
+// to Path and may contain * and ? wildcards.
+func (s3 *S3) ObjectARN(pattern string) string {
+	return s3.BucketARN() + "/" + s3.prefix(pattern)
+}
+
+// Validate checks the policy for mistakes S3 would reject, like missing
+// principals, actions of other services or unknown condition operators.
+func (p *BucketPolicy) Validate() error {
+	if p.Version != PolicyVersion && p.Version != "2008-10-17" {
+		return fmt.Errorf("s3: invalid policy version %q", p.Version)
+	}
+	if len(p.Statements) == 0 {
+		return errors.New("s3: policy has no statements")
+	}
+	sids := make(map[string]bool)
+	for i, s := range p.Statements {
+		if err := s.validate(); err != nil {
+			return fmt.Errorf("s3: policy statement %d: %v", i, err)
+		}
+		if s.Sid != "" && sids[s.Sid] {
+			return fmt.Errorf("s3: policy statement %d: duplicate sid %q", i, s.Sid)
+		}
+		sids[s.Sid] = true
+	}
+	return nil
+}
+
+func (s *Statement) validate() error {
+	if s.Effect != PolicyAllow && s.Effect != PolicyDeny {
+		return fmt.Errorf("invalid effect %q", s.Effect)
+	}
+
+	if (s.Principal == nil) == (s.NotPrincipal == nil) {
+		return errors.New("needs either a principal or not principal")
+	}
+	for _, p := range []*Principal{s.Principal, s.NotPrincipal} {
+		if p != nil && !p.Everyone && len(p.AWS)+len(p.Service)+len(p.CanonicalUser)+len(p.Federated) == 0 {
+			return errors.New("empty principal")
+		}
+	}
+
+	if (len(s.Actions) == 0) == (len(s.NotActions) == 0) {
+		return errors.New("needs either actions or not actions")
+	}
+	for _, l := range []StringList{s.Actions, s.NotActions} {
+		for _, a := range l {
+			// service prefixes are case insensitive
+			if a != "*" && (!strings.HasPrefix(strings.ToLower(a), "s3:") || len(a) == len("s3:")) {
+				return fmt.Errorf("invalid action %q", a)
+			}
+		}
+	}
+
+	if (len(s.Resources) == 0) == (len(s.NotResources) == 0) {
+		return errors.New("needs either resources or not resources")
+	}
+	for _, l := range []StringList{s.Resources, s.NotResources} {
+		for _, r := range l {
+			if r != "*" && !(strings.HasPrefix(r, "arn:") && strings.Contains(r, ":s3:::")) {
+				return fmt.Errorf("invalid resource %q", r)
+			}
+		}
+	}
+
+	for op, keys := range s.Conditions {
+		base := strings.TrimSuffix(string(op), "IfExists")
+		if i := strings.Index(base, ":"); i >= 0 && (base[:i] == "ForAllValues" || base[:i] == "ForAnyValue") {
+			base = base[i+1:]
+		}
+		if !conditionOperators[ConditionOperator(base)] {
+			return fmt.Errorf("unknown condition operator %q", op)
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("condition %s has no keys", op)
+		}
+		for k, v := range keys {
+			if k == "" || len(v) == 0 {
+				return fmt.Errorf("condition %s has an empty key or no values", op)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Principal) MarshalJSON() ([]byte, error) {
+	if p.Everyone {
+		return []byte(`"*"`), nil
+	}
+	return json.Marshal(struct {
+		AWS           StringList `json:",omitempty"`
+		Service       StringList `json:",omitempty"`
+		CanonicalUser StringList `json:",omitempty"`
+		Federated     StringList `json:",omitempty"`
+	}{p.AWS, p.Service, p.CanonicalUser, p.Federated})
+}
+
+func (p *Principal) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		if s != "*" {
+			return fmt.Errorf("s3: invalid principal %q", s)
+		}
+		*p = Principal{Everyone: true}
+		return nil
+	}
+
+	var v struct {
+		AWS           StringList
+		Service       StringList
+		CanonicalUser StringList
+		Federated     StringList
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*p = Principal{
+		Everyone:      len(v.AWS) == 1 && v.AWS[0] == "*",
+		Service:       v.Service,
+		CanonicalUser: v.CanonicalUser,
+		Federated:     v.Federated,
+	}
+	if !p.Everyone {
+		p.AWS = v.AWS
+	}
+	return nil
+}
+
+// StringList is a list of policy values. In JSON, a single value may be given
+// without an array, and condition values may be numbers or booleans.
+type StringList []string
+
+func (l *StringList) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if len(b) > 0 && b[0] == '[' {
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return err
+		}
+	} else {
+		raw = []json.RawMessage{b}
+	}
+
+	*l = make(StringList, len(raw))
+	for i, r := range raw {
+		var s string
+		if err := json.Unmarshal(r, &s); err != nil {
+			s = string(r)
+		}
+		(*l)[i] = s
+	}
+	return nil
+}
+
+// GetBucketPolicy returns the policy of the bucket. A bucket without one
+// returns an empty policy.
+func (s3 *S3) GetBucketPolicy() (*BucketPolicy, error) {
+	req, err := http.NewRequest("GET", s3.bucketURL("?policy"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s3.do(req, 200, "error getting bucket policy")
+	if ErrorCode(err) == "NoSuchBucketPolicy" {
+		return new(BucketPolicy), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	p := new(BucketPolicy)
+	if err := json.NewDecoder(resp.Body).Decode(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PutBucketPolicy validates the policy and replaces the policy of the bucket
+func (s3 *S3) PutBucketPolicy(p *BucketPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("PUT", s3.bucketURL("?policy"), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-MD5", contentMD5(b))
+
+	resp, err := s3.do(req, 204, "error putting bucket policy")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DeleteBucketPolicy removes the policy of the bucket
+func (s3 *S3) DeleteBucketPolicy() error {
+	req, err := http.NewRequest("DELETE", s3.bucketURL("?policy"), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s3.do(req, 204, "error deleting bucket policy")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,200 @@
+package s3
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const bucketPolicyJSON = `{
+  "Version": "2012-10-17",
+  "Id": "policy",
+  "Statement": [
+    {
+      "Sid": "PublicRead",
+      "Effect": "Allow",
+      "Principal": "*",
+      "Action": "s3:GetObject",
+      "Resource": "arn:aws:s3:::bucket/public/*"
+    },
+    {
+      "Effect": "Deny",
+      "Principal": {"AWS": ["arn:aws:iam::111122223333:root", "444455556666"]},
+      "NotAction": ["s3:GetObject", "s3:ListBucket"],
+      "Resource": ["arn:aws:s3:::bucket", "arn:aws:s3:::bucket/*"],
+      "Condition": {
+        "Bool": {"aws:SecureTransport": false},
+        "NumericLessThan": {"s3:TlsVersion": 1.2},
+        "ForAnyValue:StringLikeIfExists": {"aws:PrincipalTag/team": ["a*", "b*"]}
+      }
+    }
+  ]
+}`
+
+func TestBucketPolicy(t *testing.T) {
+	var p BucketPolicy
+	if err := json.Unmarshal([]byte(bucketPolicyJSON), &p); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != "policy" || len(p.Statements) != 2 {
+		t.Fatal(p)
+	}
+
+	s := p.Statements[0]
+	if s.Sid != "PublicRead" || s.Effect != PolicyAllow || !s.Principal.Everyone {
+		t.Fatal(s)
+	}
+	if !reflect.DeepEqual(s.Actions, StringList{"s3:GetObject"}) || !reflect.DeepEqual(s.Resources, StringList{"arn:aws:s3:::bucket/public/*"}) {
+		t.Fatal(s)
+	}
+
+	s = p.Statements[1]
+	if x := s.Principal.AWS; len(x) != 2 || x[1] != "444455556666" {
+		t.Fatal(x)
+	}
+	if x := s.NotActions; len(x) != 2 || len(s.Actions) != 0 {
+		t.Fatal(x)
+	}
+	if x := s.Conditions[ConditionBool]["aws:SecureTransport"]; len(x) != 1 || x[0] != "false" {
+		t.Fatal(x)
+	}
+	if x := s.Conditions[ConditionNumericLessThan]["s3:TlsVersion"]; len(x) != 1 || x[0] != "1.2" {
+		t.Fatal(x)
+	}
+	if x := s.Conditions["ForAnyValue:StringLikeIfExists"]["aws:PrincipalTag/team"]; len(x) != 2 {
+		t.Fatal(x)
+	}
+
+	// marshal and parse again
+	b, err := json.Marshal(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p2 BucketPolicy
+	if err := json.Unmarshal(b, &p2); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, p2) {
+		t.Fatal(string(b))
+	}
+}
+
+func TestBucketPolicyBuilder(t *testing.T) {
+	s3 := &S3{Bucket: "bucket", Path: "root"}
+	p := NewBucketPolicy()
+	p.Allow("s3:GetObject").For(Everyone()).On(s3.ObjectARN("public/*"))
+	p.Deny("s3:*").For(Everyone()).On(s3.BucketARN(), s3.ObjectARN("*")).
+		When(ConditionBool, "aws:SecureTransport", "false")
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `{"Version":"2012-10-17","Statement":[` +
+		`{"Effect":"Allow","Principal":"*","Action":["s3:GetObject"],"Resource":["arn:aws:s3:::bucket/root/public/*"]},` +
+		`{"Effect":"Deny","Principal":"*","Action":["s3:*"],"Resource":["arn:aws:s3:::bucket","arn:aws:s3:::bucket/root/*"],` +
+		`"Condition":{"Bool":{"aws:SecureTransport":["false"]}}}]}`
+	if x := string(b); x != exp {
+		t.Fatal(x)
+	}
+}
+
+func TestBucketPolicyValidate(t *testing.T) {
+	valid := func() *BucketPolicy {
+		p := NewBucketPolicy()
+		p.Allow("s3:GetObject").For(AWSPrincipal("111122223333")).On("arn:aws:s3:::bucket/*")
+		return p
+	}
+	for _, c := range []struct {
+		err    string
+		modify func(p *BucketPolicy)
+	}{
+		{"version", func(p *BucketPolicy) { p.Version = "" }},
+		{"no statements", func(p *BucketPolicy) { p.Statements = nil }},
+		{"effect", func(p *BucketPolicy) { p.Statements[0].Effect = "allow" }},
+		{"duplicate sid", func(p *BucketPolicy) {
+			p.Statements[0].Sid = "A"
+			p.Allow("s3:PutObject").For(Everyone()).On("*").Sid = "A"
+		}},
+		{"principal", func(p *BucketPolicy) { p.Statements[0].Principal = nil }},
+		{"principal", func(p *BucketPolicy) { p.Statements[0].NotPrincipal = Everyone() }},
+		{"empty principal", func(p *BucketPolicy) { p.Statements[0].Principal = &Principal{} }},
+		{"actions", func(p *BucketPolicy) { p.Statements[0].Actions = nil }},
+		{"invalid action", func(p *BucketPolicy) { p.Statements[0].Actions = StringList{"ec2:RunInstances"} }},
+		{"invalid action", func(p *BucketPolicy) { p.Statements[0].Actions = StringList{"S3:"} }},
+		{"resources", func(p *BucketPolicy) { p.Statements[0].NotResources = StringList{"*"} }},
+		{"invalid resource", func(p *BucketPolicy) { p.Statements[0].Resources = StringList{"bucket/*"} }},
+		{"unknown condition operator", func(p *BucketPolicy) { p.Statements[0].When("StringMatches", "aws:UserAgent", "x") }},
+		{"no values", func(p *BucketPolicy) { p.Statements[0].When(ConditionStringEquals, "aws:UserAgent") }},
+	} {
+		p := valid()
+		c.modify(p)
+		err := p.Validate()
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatal(c.err, err)
+		}
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// a policy as written in the AWS console, with free form sids and mixed case
+// actions
+const awsBucketPolicyJSON = `{
+  "Version": "2012-10-17",
+  "Id": "Policy1415115909152",
+  "Statement": [
+    {
+      "Sid": "Allow-Get Access_1",
+      "Effect": "Allow",
+      "Principal": {"AWS": "arn:aws:iam::111122223333:user/Alice"},
+      "Action": ["S3:GetObject", "s3:GetObjectVersion"],
+      "Resource": "arn:aws:s3:::examplebucket/*"
+    },
+    {
+      "Sid": "DenyInsecureTransport",
+      "Effect": "Deny",
+      "Principal": "*",
+      "Action": "s3:*",
+      "Resource": ["arn:aws:s3:::examplebucket", "arn:aws:s3:::examplebucket/*"],
+      "Condition": {"Bool": {"aws:SecureTransport": "false"}}
+    }
+  ]
+}`
+
+func TestBucketPolicyAWS(t *testing.T) {
+	var p BucketPolicy
+	if err := json.Unmarshal([]byte(awsBucketPolicyJSON), &p); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if x := p.Statements[0]; x.Sid != "Allow-Get Access_1" || x.Actions[0] != "S3:GetObject" {
+		t.Fatal(x)
+	}
+
+	// marshal and parse again
+	b, err := json.Marshal(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p2 BucketPolicy
+	if err := json.Unmarshal(b, &p2); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, p2) {
+		t.Fatal(string(b))
+	}
+	if err := p2.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
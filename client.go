@@ -0,0 +1,113 @@
+package s3
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Client holds the credentials and connection settings shared by any number of
+// bucket handles.
+//
+//	c := &s3.Client{AccessKey: key, Secret: secret}
+//	logs := c.Bucket("logs")
+//	media := c.Bucket("media", "uploads")
+type Client struct {
+	// AccessKey is the S3 access key
+	AccessKey string
+
+	// Secret is the S3 secret
+	Secret string
+
+	// Endpoint is the scheme and host of the S3 API, e.g. http://localhost:9000
+	// for a local S3 compatible server. Defaults to https://s3.amazonaws.com.
+	Endpoint string
+
+	// HTTPClient sends all requests, so its transport holds the connection pool
+	// of all buckets. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	m       sync.Mutex
+	regions map[string]string
+}
+
+// defaultClient is used by configurations without a Client
+var defaultClient = new(Client)
+
+// Bucket returns a handle for the bucket, with an optional path prepended to
+// all keys. Handles are cheap and share the settings of the client.
+func (c *Client) Bucket(name string, path ...string) *S3 {
+	s3 := &S3{Bucket: name, Client: c}
+	if len(path) > 0 {
+		s3.Path = path[0]
+	}
+	return s3
+}
+
+// ListBuckets returns all buckets of the account
+func (c *Client) ListBuckets() ([]Bucket, error) {
+	s3 := S3{Client: c}
+	return s3.ListBuckets()
+}
+
+func (c *Client) endpoint() string {
+	if c.Endpoint != "" {
+		return strings.TrimRight(c.Endpoint, `/`)
+	}
+	return s3proto + `://` + s3host
+}
+
+// region returns the cached region of a bucket, or an empty string if it
+// wasn't discovered yet.
+func (c *Client) region(bucket string) string {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.regions[bucket]
+}
+
+func (c *Client) setRegion(bucket, region string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.regions == nil {
+		c.regions = make(map[string]string)
+	}
+	if region == "" {
+		delete(c.regions, bucket)
+	} else {
+		c.regions[bucket] = region
+	}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// client returns the client of the configuration, or the default one
+func (s3 *S3) client() *Client {
+	if s3.Client != nil {
+		return s3.Client
+	}
+	return defaultClient
+}
+
+// endpoint returns the scheme and host for requests to the bucket. Without a
+// custom endpoint, this is the endpoint of the bucket region once it is known.
+func (s3 *S3) endpoint() string {
+	c := s3.client()
+	if c.Endpoint != "" || s3.Bucket == "" {
+		return c.endpoint()
+	}
+	return regionEndpoint(c.region(s3.Bucket))
+}
+
+// credentials returns the access key and secret. Keys set on the configuration
+// take precedence over the ones of the client.
+func (s3 *S3) credentials() (key, secret string) {
+	if s3.AccessKey == "" && s3.Client != nil {
+		return s3.Client.AccessKey, s3.Client.Secret
+	}
+	return s3.AccessKey, s3.Secret
+}
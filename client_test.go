@@ -0,0 +1,231 @@
+package s3
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientBucket(t *testing.T) {
+	c := &Client{AccessKey: "key", Secret: "secret", Endpoint: "http://localhost:9000/"}
+	b := c.Bucket("bucket", "root")
+	if b.Bucket != "bucket" || b.Path != "root" || b.Client != c {
+		t.Fatal(b)
+	}
+	if key, secret := b.credentials(); key != "key" || secret != "secret" {
+		t.Fatal(key, secret)
+	}
+	if x := b.Object("a.txt").(*object).url(""); x != "http://localhost:9000/bucket/root/a.txt" {
+		t.Fatal(x)
+	}
+
+	// keys of the configuration take precedence
+	b.AccessKey, b.Secret = "key2", "secret2"
+	if key, secret := b.credentials(); key != "key2" || secret != "secret2" {
+		t.Fatal(key, secret)
+	}
+
+	s := S3{Bucket: "bucket"}
+	if x := s.bucketURL(""); x != "https://s3.amazonaws.com/bucket/" {
+		t.Fatal(x)
+	}
+}
+
+func TestClientRequests(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS key:") {
+			w.WriteHeader(403)
+			return
+		}
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.Header().Set("ETag", `"etag"`)
+	}))
+	defer srv.Close()
+
+	c := &Client{AccessKey: "key", Secret: "secret", Endpoint: srv.URL, HTTPClient: srv.Client()}
+	for _, name := range []string{"a", "b"} {
+		if _, err := c.Bucket(name).Object("x.txt").Put(strings.NewReader("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if x := strings.Join(paths, ","); x != "PUT /a/x.txt,PUT /b/x.txt" {
+		t.Fatal(x)
+	}
+}
+
+type roundTripFunc func(*http.Request) *http.Response
+
+func (fn roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req), nil
+}
+
+func testResponse(code int, h http.Header, body string) *http.Response {
+	if h == nil {
+		h = make(http.Header)
+	}
+	return &http.Response{
+		StatusCode: code,
+		Header:     h,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestRegionRedirect(t *testing.T) {
+	var reqs []string
+	c := &Client{AccessKey: "key", Secret: "secret"}
+	c.HTTPClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		b, _ := ioutil.ReadAll(req.Body)
+		auth := req.Header.Get("Authorization")
+		if i := strings.Index(auth, "/s3/"); strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") && i > 0 {
+			// the region of the credential scope
+			auth = "v4:" + auth[strings.LastIndex(auth[:i], "/")+1:i]
+		} else if strings.HasPrefix(auth, "AWS key:") {
+			auth = "v2"
+		}
+		reqs = append(reqs, auth+" "+req.URL.Host+req.URL.Path)
+		if string(b) != "data" {
+			return testResponse(403, nil, "")
+		}
+
+		switch req.URL.Host {
+		case "s3.amazonaws.com":
+			switch req.URL.Path {
+			case "/moved/a.txt":
+				return testResponse(301, http.Header{"X-Amz-Bucket-Region": {"eu-west-1"}}, "")
+			case "/v4only/a.txt":
+				return testResponse(400, http.Header{"X-Amz-Bucket-Region": {"eu-central-1"}}, `<Error><Code>InvalidRequest</Code></Error>`)
+			}
+			return testResponse(400, nil, `<Error><Code>AuthorizationHeaderMalformed</Code><Region>ap-south-1</Region></Error>`)
+		case "s3.ap-south-1.amazonaws.com":
+			return testResponse(307, http.Header{"Location": {"https://malformed.s3-ap-south-1.amazonaws.com/a.txt"}}, "")
+		}
+		return testResponse(200, nil, "")
+	})}
+
+	for bucket, region := range map[string]string{"moved": "eu-west-1", "malformed": "ap-south-1", "v4only": "eu-central-1"} {
+		reqs = nil
+		if _, err := c.Bucket(bucket).Object("a.txt").Put(strings.NewReader("data")); err != nil {
+			t.Fatal(err)
+		}
+		if x := c.region(bucket); x != region {
+			t.Fatal(x)
+		}
+		exp := map[string]string{
+			"moved":     "v2 s3.amazonaws.com/moved/a.txt,v4:eu-west-1 s3.eu-west-1.amazonaws.com/moved/a.txt",
+			"malformed": "v2 s3.amazonaws.com/malformed/a.txt,v4:ap-south-1 s3.ap-south-1.amazonaws.com/malformed/a.txt,v4:ap-south-1 s3-ap-south-1.amazonaws.com/malformed/a.txt",
+			"v4only":    "v2 s3.amazonaws.com/v4only/a.txt,v4:eu-central-1 s3.eu-central-1.amazonaws.com/v4only/a.txt",
+		}[bucket]
+		if x := strings.Join(reqs, ","); x != exp {
+			t.Fatal(x)
+		}
+	}
+
+	// the cached region is used right away
+	reqs = nil
+	if _, err := c.Bucket("moved").Object("b.txt").Put(strings.NewReader("data")); err != nil {
+		t.Fatal(err)
+	}
+	if x := strings.Join(reqs, ","); x != "v4:eu-west-1 s3.eu-west-1.amazonaws.com/moved/b.txt" {
+		t.Fatal(x)
+	}
+}
+
+func TestRegionLocationKeepsCache(t *testing.T) {
+	c := &Client{AccessKey: "key", Secret: "secret"}
+	c.setRegion("bucket", "eu-west-1")
+	c.HTTPClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		switch req.URL.Host {
+		case "s3.eu-west-1.amazonaws.com":
+			return testResponse(301, nil, `<Error><Code>PermanentRedirect</Code></Error>`)
+		case "s3.amazonaws.com":
+			// concurrent requests still see the cached region
+			if x := c.region("bucket"); x != "eu-west-1" || req.URL.RawQuery != "location" {
+				t.Error(x, req.URL)
+			}
+			return testResponse(200, nil, `<LocationConstraint>eu-north-1</LocationConstraint>`)
+		}
+		return testResponse(200, nil, "")
+	})}
+
+	if _, err := c.Bucket("bucket").Object("a.txt").Put(strings.NewReader("data")); err != nil {
+		t.Fatal(err)
+	}
+	if x := c.region("bucket"); x != "eu-north-1" {
+		t.Fatal(x)
+	}
+}
+
+func TestRegionRedirectError(t *testing.T) {
+	c := &Client{}
+	c.HTTPClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		return testResponse(400, nil, `<Error><Code>InvalidArgument</Code></Error>`)
+	})}
+
+	_, err := c.Bucket("bucket").Object("a.txt").Put(strings.NewReader("data"))
+	e, ok := err.(*s3err)
+	if !ok || e.code != 400 || !strings.Contains(e.xmlBody, "InvalidArgument") {
+		t.Fatal(err)
+	}
+	if x := c.region("bucket"); x != "" {
+		t.Fatal(x)
+	}
+}
+
+func TestExists(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	c := &Client{Endpoint: "http://s3.test", HTTPClient: &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) *http.Response {
+			switch {
+			case req.URL.Path == "/b/missing":
+				return testResponse(404, nil, "")
+			case req.Header.Get("x-amz-server-side-encryption-customer-key") == "":
+				return testResponse(400, nil, "<Error><Code>InvalidRequest</Code></Error>")
+			}
+			return testResponse(200, nil, "")
+		}),
+	}}
+	s3 := c.Bucket("b")
+
+	if ok, err := s3.Object("missing").Exists(); ok || err != nil {
+		t.Fatal(ok, err)
+	}
+	if ok, err := s3.Object("sse").Exists(); ok || ErrorCode(err) != "InvalidRequest" {
+		t.Fatal(ok, err)
+	}
+	ok, err := s3.Object("sse").Exists(ReadOptions{Encryption: &Encryption{CustomerKey: key}})
+	if !ok || err != nil {
+		t.Fatal(ok, err)
+	}
+}
+
+func TestDeleteMarkerHeader(t *testing.T) {
+	c := &Client{Endpoint: "http://s3.test", HTTPClient: &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) *http.Response {
+			h := http.Header{
+				"X-Amz-Delete-Marker": {"true"},
+				"X-Amz-Version-Id":    {"v2"},
+			}
+			if req.Method == "HEAD" {
+				return testResponse(404, h, "")
+			}
+			return testResponse(405, h, "<Error><Code>MethodNotAllowed</Code></Error>")
+		}),
+	}}
+	o := c.Bucket("b").Object("a.txt")
+
+	_, err := o.Head()
+	if h := ErrorHeader(err); !h.DeleteMarker() || h.VersionID() != "v2" {
+		t.Fatal(err, h)
+	}
+	_, _, err = o.Reader(ReadOptions{VersionID: "v2"})
+	if h := ErrorHeader(err); !h.DeleteMarker() || ErrorCode(err) != "MethodNotAllowed" {
+		t.Fatal(err, h)
+	}
+	if h := ErrorHeader(errors.New("other")); h != nil {
+		t.Fatal(h)
+	}
+}
@@ -0,0 +1,200 @@
+package s3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var errNothingToCompose = errors.New("s3: no objects to compose")
+
+// composePart is a part of a composed object. Copied parts consist of a single
+// range of a source, all other parts are assembled from downloaded ranges.
+type composePart struct {
+	copy   bool
+	pieces []composePiece
+}
+
+type composePiece struct {
+	src int
+	off int64
+	n   int64
+}
+
+// ComposeOptions configure Compose. The embedded WriteOptions apply to the
+// composed object.
+type ComposeOptions struct {
+	WriteOptions
+
+	// SourceEncryption holds the customer keys of SSE-C encrypted sources at
+	// the index of the source. Other sources may be nil or left out.
+	SourceEncryption []*Encryption
+}
+
+// sourceEncryption returns the encryption of source i
+func (co *ComposeOptions) sourceEncryption(i int) *Encryption {
+	if i < len(co.SourceEncryption) {
+		return co.SourceEncryption[i]
+	}
+	return nil
+}
+
+// Compose concatenates the sources into dst on the server side. Sources of at
+// least MinPartSize are copied as parts of a multipart upload. Smaller ones
+// can't be parts on their own, so they are downloaded and combined with their
+// neighbours into parts of valid size.
+func (c *Copier) Compose(dst Object, srcs []Object, opts ...ComposeOptions) (*CopyResult, error) {
+	if len(srcs) == 0 {
+		return nil, errNothingToCompose
+	}
+	var co ComposeOptions
+	if len(opts) > 0 {
+		co = opts[0]
+	}
+	d := plainObject(dst)
+	wo := co.WriteOptions
+	wo.Compression = ""
+
+	objs := make([]*object, len(srcs))
+	sizes := make([]int64, len(srcs))
+	etags := make([]string, len(srcs))
+	for i, src := range srcs {
+		objs[i] = plainObject(src)
+		h, err := objs[i].Head(ReadOptions{Encryption: co.sourceEncryption(i)})
+		if err != nil {
+			return nil, err
+		}
+		if sizes[i], err = h.ContentLength(); err != nil {
+			return nil, err
+		}
+		etags[i] = h.ETag()
+	}
+
+	plan := c.composeParts(sizes)
+	if n := len(plan); n > MaxNumParts {
+		return nil, fmt.Errorf("s3: composing needs %d parts, more than %d", n, MaxNumParts)
+	}
+
+	uploadId, err := initiateUpload(d, wo)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]*part, len(plan))
+	copies := make([]int, 0, len(plan))
+	for i, cp := range plan {
+		parts[i] = &part{PartNumber: i + 1}
+		if cp.copy {
+			copies = append(copies, i)
+			continue
+		}
+
+		var buf bytes.Buffer
+		for _, pc := range cp.pieces {
+			ro := ReadOptions{
+				Encryption: co.sourceEncryption(pc.src),
+				Offset:     pc.off,
+				Length:     pc.n,
+				IfMatch:    etags[pc.src],
+			}
+			if err = download(&buf, objs[pc.src], ro); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			parts[i].buf = buf.Bytes()
+			err = uploadPart(d, uploadId, parts[i], wo.Encryption)
+			parts[i].buf = nil
+		}
+		if err != nil {
+			abortUpload(d, uploadId)
+			return nil, err
+		}
+	}
+
+	err = runParts(len(copies), c.Concurrency, func(j int) error {
+		i := copies[j]
+		pc := plan[i].pieces[0]
+		cpo := CopyOptions{
+			WriteOptions:     wo,
+			SourceIfMatch:    etags[pc.src],
+			SourceEncryption: co.sourceEncryption(pc.src),
+		}
+		return copyPart(objs[pc.src], d, uploadId, parts[i], [2]int64{pc.off, pc.off + pc.n - 1}, &cpo)
+	})
+	if err != nil {
+		abortUpload(d, uploadId)
+		return nil, err
+	}
+
+	return finishUpload(d, uploadId, parts, wo)
+}
+
+// composeParts plans the parts for concatenating objects of the given sizes.
+// Every part but the last is at least MinPartSize.
+func (c *Copier) composeParts(sizes []int64) []composePart {
+	var (
+		parts      []composePart
+		pending    composePart
+		pendingLen int64
+	)
+	flush := func() {
+		parts = append(parts, pending)
+		pending = composePart{}
+		pendingLen = 0
+	}
+	add := func(src int, off, n int64) {
+		pending.pieces = append(pending.pieces, composePiece{src, off, n})
+		pendingLen += n
+	}
+
+	for i, size := range sizes {
+		off := int64(0)
+
+		// top up downloaded data from previous sources to a valid part
+		if pendingLen > 0 {
+			n := MinPartSize - pendingLen
+			if n > size {
+				n = size
+			}
+			if n > 0 {
+				add(i, 0, n)
+				off = n
+			}
+			if pendingLen >= MinPartSize {
+				flush()
+			}
+		}
+
+		rest := size - off
+		switch {
+		case rest == 0:
+		case rest < MinPartSize:
+			add(i, off, rest)
+		default:
+			for _, r := range c.ranges(rest) {
+				parts = append(parts, composePart{
+					copy:   true,
+					pieces: []composePiece{{i, off + r[0], r[1] - r[0] + 1}},
+				})
+			}
+		}
+	}
+	if pendingLen > 0 || len(parts) == 0 {
+		flush()
+	}
+	return parts
+}
+
+// download appends the range of o selected by ro to w. ro carries the ETag seen
+// when planning, so the read fails if o was replaced since.
+func download(w io.Writer, o *object, ro ReadOptions) error {
+	r, _, err := o.Reader(ro)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
@@ -0,0 +1,156 @@
+package s3
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Compression is a content coding applied to objects on upload. Only Gzip is
+// built in. Reading or writing other codings, e.g. zstd, needs a codec
+// registered with RegisterCompression, like this one using
+// github.com/klauspost/compress/zstd:
+//
+//	s3.RegisterCompression("zstd", s3.Codec{
+//		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+//			return zstd.NewWriter(w)
+//		},
+//		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+//			d, err := zstd.NewReader(r)
+//			if err != nil {
+//				return nil, err
+//			}
+//			return d.IOReadCloser(), nil
+//		},
+//	})
+type Compression string
+
+const Gzip Compression = "gzip"
+
+const metaCompression = "compression"
+
+// Codec creates the streams for a Compression
+type Codec struct {
+	NewWriter func(w io.Writer) (io.WriteCloser, error)
+	NewReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[Compression]Codec{
+		Gzip: {
+			NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+				return gzip.NewWriter(w), nil
+			},
+			NewReader: func(r io.Reader) (io.ReadCloser, error) {
+				return gzip.NewReader(r)
+			},
+		},
+	}
+)
+
+var errCompressedRange = errors.New("s3: ranged reads of compressed objects are not supported")
+
+// RegisterCompression makes a codec available for compressing and
+// decompressing objects, e.g. to add zstd.
+func RegisterCompression(c Compression, codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c] = codec
+}
+
+func codecFor(c Compression) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	codec, ok := codecs[c]
+	if !ok {
+		return Codec{}, fmt.Errorf("s3: unsupported compression %q, see RegisterCompression", c)
+	}
+	return codec, nil
+}
+
+// compression returns the compression of an object, taken from the metadata
+// or, for objects written by other tools, the Content-Encoding header.
+func compression(h Header) Compression {
+	if c := h.Metadata()[metaCompression]; c != "" {
+		return Compression(c)
+	}
+	c := Compression(h.ContentEncoding())
+	if _, err := codecFor(c); err == nil {
+		return c
+	}
+	return ""
+}
+
+func compress(w io.Writer, c Compression) (io.WriteCloser, error) {
+	codec, err := codecFor(c)
+	if err != nil {
+		return nil, err
+	}
+	return codec.NewWriter(w)
+}
+
+// decompressStream wraps r in a reader for c. Closing it closes r as well.
+func decompressStream(r io.ReadCloser, c Compression) (io.ReadCloser, error) {
+	codec, err := codecFor(c)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := codec.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &decompressReader{zr, r}, nil
+}
+
+type decompressReader struct {
+	io.ReadCloser
+	r io.ReadCloser
+}
+
+func (d *decompressReader) Close() error {
+	err := d.ReadCloser.Close()
+	if err2 := d.r.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// compressWriter compresses everything written before passing it to w
+type compressWriter struct {
+	zw  io.WriteCloser
+	w   Writer
+	err error
+}
+
+func newCompressWriter(w Writer, c Compression) Writer {
+	zw, err := compress(w, c)
+	return &compressWriter{zw: zw, w: w, err: err}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	return cw.zw.Write(p)
+}
+
+func (cw *compressWriter) Close() error {
+	if cw.err != nil {
+		return cw.err
+	}
+	if err := cw.zw.Close(); err != nil {
+		cw.w.Abort()
+		return err
+	}
+	return cw.w.Close()
+}
+
+func (cw *compressWriter) Abort() error {
+	if cw.err != nil {
+		return cw.err
+	}
+	return cw.w.Abort()
+}
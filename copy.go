@@ -0,0 +1,355 @@
+package s3
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MaxCopySize is the largest object a single CopyObject request can copy
+const MaxCopySize = 5 * 1024 * 1024 * 1024
+
+type MetadataDirective string
+
+const (
+	// CopyMetadata keeps the metadata and headers of the source object
+	CopyMetadata MetadataDirective = "COPY"
+
+	// ReplaceMetadata replaces the metadata and headers with the ones in the
+	// copy options.
+	ReplaceMetadata MetadataDirective = "REPLACE"
+)
+
+// CopyOptions configure server side copies. The embedded WriteOptions apply to
+// the destination, though metadata and standard headers are only used with
+// ReplaceMetadata. Compression is ignored.
+type CopyOptions struct {
+	WriteOptions
+
+	MetadataDirective MetadataDirective
+
+	// Conditions on the source object. If they don't hold, the copy fails with
+	// ErrPreconditionFailed.
+	SourceIfMatch           string
+	SourceIfNoneMatch       string
+	SourceIfModifiedSince   time.Time
+	SourceIfUnmodifiedSince time.Time
+
+	// SourceEncryption must carry the customer key if the source was written
+	// with SSE-C.
+	SourceEncryption *Encryption
+
+	// SourceVersionID copies a specific version of the source instead of the
+	// latest
+	SourceVersionID string
+}
+
+func copyOptions(opts []CopyOptions) CopyOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return CopyOptions{}
+}
+
+// setHeaders adds the headers for copying src to h
+func (co *CopyOptions) setHeaders(h http.Header, src, dst *object) {
+	h.Set("x-amz-copy-source", src.copySource(co.SourceVersionID))
+	if co.MetadataDirective == ReplaceMetadata {
+		h.Set("x-amz-metadata-directive", string(co.MetadataDirective))
+		h.Set("Content-Type", dst.contentType())
+	}
+	wo := co.WriteOptions
+	wo.Compression = ""
+	wo.setHeaders(h)
+	wo.setConditions(h)
+	co.setSourceHeaders(h)
+}
+
+// setSourceHeaders adds the conditions and SSE-C key of the source to h
+func (co *CopyOptions) setSourceHeaders(h http.Header) {
+	co.SourceEncryption.setCopySourceHeaders(h)
+	for k, v := range map[string]string{
+		"x-amz-copy-source-if-match":      co.SourceIfMatch,
+		"x-amz-copy-source-if-none-match": co.SourceIfNoneMatch,
+	} {
+		if v != "" {
+			h.Set(k, v)
+		}
+	}
+	for k, v := range map[string]time.Time{
+		"x-amz-copy-source-if-modified-since":   co.SourceIfModifiedSince,
+		"x-amz-copy-source-if-unmodified-since": co.SourceIfUnmodifiedSince,
+	} {
+		if !v.IsZero() {
+			h.Set(k, v.UTC().Format(http.TimeFormat))
+		}
+	}
+}
+
+// CopyResult describes the object created by a copy
+type CopyResult struct {
+	ETag         string
+	LastModified time.Time
+	VersionID    string
+}
+
+func (o *object) CopyTo(dst Object, opts ...CopyOptions) (*CopyResult, error) {
+	d := plainObject(dst)
+	co := copyOptions(opts)
+
+	req, err := http.NewRequest("PUT", d.url(""), nil)
+	if err != nil {
+		return nil, err
+	}
+	co.setHeaders(req.Header, o, d)
+
+	resp, err := d.s3.do(req, 200, "error copying object")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	res, err := readResult(resp, "error copying object")
+	if err != nil {
+		return nil, err
+	}
+	res.VersionID = resp.Header.Get("x-amz-version-id")
+	return res, nil
+}
+
+// copySource returns the value of the x-amz-copy-source header for a version of
+// o, or the latest if versionID is empty.
+func (o *object) copySource(versionID string) string {
+	cres, _ := canonicalResource(o.resource(""), nil)
+	return cres + versionQuery(versionID)
+}
+
+// readResult parses a CopyObjectResult, CopyPartResult or
+// CompleteMultipartUploadResult. S3 may fail these requests after it already
+// sent the 200 status, in which case the body holds an error document instead.
+func readResult(resp *http.Response, serr string) (*CopyResult, error) {
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var v struct {
+		XMLName      xml.Name
+		ETag         string
+		LastModified string
+		Code         string
+	}
+	if err := xml.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	if v.XMLName.Local == "Error" {
+		return nil, &s3err{
+			code:    resp.StatusCode,
+			text:    fmt.Sprintf("s3: %s (%s)", serr, v.Code),
+			xmlBody: string(b),
+			header:  resp.Header,
+		}
+	}
+
+	res := &CopyResult{ETag: trimETag(v.ETag)}
+	if v.LastModified != "" {
+		res.LastModified, err = time.Parse(time.RFC3339, v.LastModified)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// Copier copies objects of any size on the server side. Objects larger than
+// MaxCopySize are copied as a multipart upload with ranges of the source copied
+// in parallel.
+type Copier struct {
+	// PartSize is the size of the copied ranges. It is raised as needed to stay
+	// within MaxNumParts. Defaults to 256 MB.
+	PartSize int64
+
+	// Concurrency is the number of ranges copied at once. Defaults to 5.
+	Concurrency int
+}
+
+const defaultCopyPartSize = 256 * 1024 * 1024
+
+// Copy copies src to dst. Unless SourceIfMatch is set, the parts of a multipart
+// copy are pinned to the ETag seen before the copy, so a source replaced in the
+// meantime fails the copy with ErrPreconditionFailed. Like a plain copy, the
+// ACL, tags and storage class of the source are not carried over, they are set
+// by the options.
+func (c *Copier) Copy(src, dst Object, opts ...CopyOptions) (*CopyResult, error) {
+	s, d := plainObject(src), plainObject(dst)
+	co := copyOptions(opts)
+
+	h, err := s.Head(ReadOptions{Encryption: co.SourceEncryption, VersionID: co.SourceVersionID})
+	if err != nil {
+		return nil, err
+	}
+	size, err := h.ContentLength()
+	if err != nil {
+		return nil, err
+	}
+	if size <= MaxCopySize {
+		return s.CopyTo(d, co)
+	}
+	if co.SourceIfMatch == "" {
+		co.SourceIfMatch = h.ETag()
+	}
+
+	// a multipart upload doesn't take over the source metadata by itself
+	wo := co.WriteOptions
+	wo.Compression = ""
+	if co.MetadataDirective != ReplaceMetadata {
+		wo.Metadata = h.Metadata()
+		wo.ContentType = h.ContentType()
+		wo.CacheControl = h.CacheControl()
+		wo.ContentDisposition = h.ContentDisposition()
+		wo.ContentEncoding = h.ContentEncoding()
+		wo.ContentLanguage = h.ContentLanguage()
+		wo.Expires, _ = h.Expires()
+	}
+
+	ranges := c.ranges(size)
+	parts := make([]*part, len(ranges))
+	for i := range parts {
+		parts[i] = &part{PartNumber: i + 1}
+	}
+
+	uploadId, err := initiateUpload(d, wo)
+	if err != nil {
+		return nil, err
+	}
+
+	err = runParts(len(parts), c.Concurrency, func(i int) error {
+		return copyPart(s, d, uploadId, parts[i], ranges[i], &co)
+	})
+	if err != nil {
+		abortUpload(d, uploadId)
+		return nil, err
+	}
+
+	return finishUpload(d, uploadId, parts, wo)
+}
+
+// ranges splits size bytes into the byte ranges of the parts
+func (c *Copier) ranges(size int64) [][2]int64 {
+	partSize := c.PartSize
+	if partSize <= 0 {
+		partSize = defaultCopyPartSize
+	}
+	if partSize < MinPartSize {
+		partSize = MinPartSize
+	}
+	if n := (size + MaxNumParts - 1) / MaxNumParts; partSize < n {
+		partSize = n
+	}
+
+	var r [][2]int64
+	for off := int64(0); off < size; off += partSize {
+		end := off + partSize
+		if end > size {
+			end = size
+		}
+		r = append(r, [2]int64{off, end - 1})
+	}
+
+	// merge a short remainder into the previous range, so the ranges can be
+	// followed by more parts
+	if n := len(r); n > 1 && r[n-1][1]-r[n-1][0]+1 < MinPartSize {
+		r[n-2][1] = r[n-1][1]
+		r = r[:n-1]
+	}
+	return r
+}
+
+// runParts calls fn for the parts 0 to n-1, at most concurrency at once, and
+// retries failed parts. After a part failed no new parts are started and its
+// error is returned.
+func runParts(n, concurrency int, fn func(i int) error) error {
+	if concurrency <= 0 {
+		concurrency = nConcurrentUploads
+	}
+
+	var (
+		wg       sync.WaitGroup
+		m        sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, concurrency)
+
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+
+		m.Lock()
+		failed := firstErr != nil
+		m.Unlock()
+		if failed {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			var err error
+			for r := 0; r < nRetries; r++ {
+				err = fn(i)
+				if err == nil || errors.Is(err, ErrPreconditionFailed) {
+					break
+				}
+			}
+			if err != nil {
+				m.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				m.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+// copyPart copies the byte range r of src into part p of a multipart upload of
+// dst.
+func copyPart(src, dst *object, uploadId string, p *part, r [2]int64, co *CopyOptions) error {
+	uv := make(url.Values)
+	uv.Set("partNumber", strconv.Itoa(p.PartNumber))
+	uv.Set("uploadId", uploadId)
+
+	req, err := http.NewRequest("PUT", dst.url(`?`+uv.Encode()), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-amz-copy-source", src.copySource(co.SourceVersionID))
+	req.Header.Set("x-amz-copy-source-range", fmt.Sprintf("bytes=%d-%d", r[0], r[1]))
+	co.setSourceHeaders(req.Header)
+	co.Encryption.setCustomerHeaders(req.Header, ssePrefix)
+
+	resp, err := dst.s3.do(req, 200, "could not copy part")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	res, err := readResult(resp, "could not copy part")
+	if err != nil {
+		return err
+	}
+	p.ETag = res.ETag
+	return nil
+}
@@ -0,0 +1,380 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCopyHeaders(t *testing.T) {
+	c := &S3{Bucket: "bücket"}
+	src := c.Object("a/ü b.txt").(*object)
+	dst := c.Object("b/c.json").(*object)
+
+	h := make(http.Header)
+	co := CopyOptions{
+		WriteOptions:          WriteOptions{Metadata: map[string]string{"a": "b"}},
+		MetadataDirective:     ReplaceMetadata,
+		SourceIfMatch:         "etag",
+		SourceIfModifiedSince: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	co.setHeaders(h, src, dst)
+
+	if x := h.Get("x-amz-copy-source"); x != "/b%C3%BCcket/a/%C3%BC%20b.txt" {
+		t.Fatal(x)
+	}
+	if x := h.Get("x-amz-metadata-directive"); x != "REPLACE" {
+		t.Fatal(x)
+	}
+	if x := h.Get("Content-Type"); x != "application/json" {
+		t.Fatal(x)
+	}
+	if x := h.Get("x-amz-meta-a"); x != "b" {
+		t.Fatal(x)
+	}
+	if x := h.Get("x-amz-copy-source-if-match"); x != "etag" {
+		t.Fatal(x)
+	}
+	if x := h.Get("x-amz-copy-source-if-modified-since"); x != "Thu, 02 Jan 2020 03:04:05 GMT" {
+		t.Fatal(x)
+	}
+	if x := h.Get("x-amz-copy-source-if-none-match"); x != "" {
+		t.Fatal(x)
+	}
+}
+
+func TestReadCopyResult(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: 200,
+		Body: ioutil.NopCloser(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<CopyObjectResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <LastModified>2009-10-12T17:50:30.000Z</LastModified>
+  <ETag>"9b2cf535f27731c974343645a3985328"</ETag>
+</CopyObjectResult>`)),
+	}
+	res, err := readResult(resp, "copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := res.ETag; x != "9b2cf535f27731c974343645a3985328" {
+		t.Fatal(x)
+	}
+	if x := res.LastModified; !x.Equal(time.Date(2009, 10, 12, 17, 50, 30, 0, time.UTC)) {
+		t.Fatal(x)
+	}
+
+	// error after the 200 status was sent
+	resp.Body = ioutil.NopCloser(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<Error>
+  <Code>InternalError</Code>
+  <Message>We encountered an internal error. Please try again.</Message>
+</Error>`))
+	res, err = readResult(resp, "copy")
+	if err == nil {
+		t.Fatal(res)
+	}
+	if x := err.Error(); x != "s3: copy (InternalError)" {
+		t.Fatal(x)
+	}
+	var e *s3err
+	if !errors.As(err, &e) || e.code != 200 {
+		t.Fatal(err)
+	}
+}
+
+func TestCopierRanges(t *testing.T) {
+	c := &Copier{PartSize: 1}
+	r := c.ranges(16 * 1024 * 1024)
+	if x := len(r); x != 3 {
+		t.Fatal(x)
+	}
+	if x := r[0]; x != [2]int64{0, MinPartSize - 1} {
+		t.Fatal(x)
+	}
+	if x := r[2]; x != [2]int64{2 * MinPartSize, 16*1024*1024 - 1} {
+		t.Fatal(x)
+	}
+
+	// no short range before the end
+	r = c.ranges(12 * 1024 * 1024)
+	if x := len(r); x != 2 {
+		t.Fatal(x)
+	}
+	if x := r[1]; x != [2]int64{MinPartSize, 12*1024*1024 - 1} {
+		t.Fatal(x)
+	}
+
+	// stay within the part limit
+	c = &Copier{}
+	size := int64(MaxObjectSize)
+	r = c.ranges(size)
+	if x := len(r); x > MaxNumParts {
+		t.Fatal(x)
+	}
+	if x := r[len(r)-1][1]; x != size-1 {
+		t.Fatal(x)
+	}
+}
+
+func TestRunParts(t *testing.T) {
+	var m sync.Mutex
+	done := make(map[int]int)
+	err := runParts(20, 3, func(i int) error {
+		m.Lock()
+		defer m.Unlock()
+		done[i]++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := len(done); x != 20 {
+		t.Fatal(x)
+	}
+
+	// failed parts are retried, then the first error is returned
+	fail := errors.New("fail")
+	calls := 0
+	err = runParts(100, 1, func(i int) error {
+		m.Lock()
+		defer m.Unlock()
+		calls++
+		if i == 2 {
+			return fail
+		}
+		return nil
+	})
+	if err != fail {
+		t.Fatal(err)
+	}
+	if calls != 2+nRetries {
+		t.Fatal(calls)
+	}
+}
+
+func TestComposeParts(t *testing.T) {
+	const mb = 1024 * 1024
+	c := &Copier{PartSize: 8 * mb}
+
+	// kind and size of each part
+	check := func(sizes []int64, want string) {
+		t.Helper()
+		var got []string
+		var total, sum int64
+		for _, s := range sizes {
+			total += s
+		}
+		parts := c.composeParts(sizes)
+		for i, p := range parts {
+			var n int64
+			for _, pc := range p.pieces {
+				n += pc.n
+			}
+			sum += n
+			if i < len(parts)-1 && n < MinPartSize {
+				t.Fatal(sizes, i, n)
+			}
+			kind := "d"
+			if p.copy {
+				kind = "c"
+			}
+			got = append(got, fmt.Sprintf("%s%d", kind, n/mb))
+		}
+		if sum != total {
+			t.Fatal(sizes, sum, total)
+		}
+		if x := strings.Join(got, " "); x != want {
+			t.Fatal(sizes, x)
+		}
+	}
+
+	check([]int64{}, "d0")
+	check([]int64{1 * mb, 1 * mb}, "d2")
+	check([]int64{6 * mb, 6 * mb}, "c6 c6")
+	check([]int64{20 * mb}, "c8 c12")
+	check([]int64{1 * mb, 1 * mb, 10 * mb}, "d5 c7")
+	check([]int64{3 * mb, 7 * mb, 1 * mb}, "d5 c5 d1")
+	check([]int64{6 * mb, 2 * mb, 2 * mb, 2 * mb, 6 * mb}, "c6 d5 d5 d2")
+	check([]int64{6 * mb, 2 * mb, 2 * mb, 2 * mb, 12 * mb}, "c6 d5 d5 c8")
+}
+
+// fakeMoveTransport keeps objects by key and their ETags. tamper changes the
+// content of copies, which shows in the ETag reported by a later HEAD.
+func fakeMoveTransport(objects map[string]string, tamper bool) http.RoundTripper {
+	var m sync.Mutex
+	return roundTripFunc(func(req *http.Request) *http.Response {
+		m.Lock()
+		defer m.Unlock()
+
+		key := strings.TrimPrefix(req.URL.Path, "/b/")
+		switch {
+		case req.Method == "GET" && key == "":
+			var b strings.Builder
+			b.WriteString("<ListBucketResult>")
+			for k := range objects {
+				if strings.HasPrefix(k, req.URL.Query().Get("prefix")) {
+					fmt.Fprintf(&b, "<Contents><Key>%s</Key><Size>1</Size></Contents>", k)
+				}
+			}
+			b.WriteString("</ListBucketResult>")
+			return testResponse(200, nil, b.String())
+		case req.Method == "HEAD":
+			etag, ok := objects[key]
+			if !ok {
+				return testResponse(404, nil, "")
+			}
+			return testResponse(200, http.Header{"Etag": {`"` + etag + `"`}, "Content-Length": {"1"}}, "")
+		case req.Method == "PUT":
+			src, _ := url.PathUnescape(strings.TrimPrefix(req.Header.Get("x-amz-copy-source"), "/b/"))
+			etag, ok := objects[src]
+			if !ok {
+				return testResponse(404, nil, "")
+			}
+			if tamper {
+				etag = "tampered"
+			}
+			objects[key] = etag
+			return testResponse(200, nil, "<CopyObjectResult><ETag>&quot;"+etag+"&quot;</ETag></CopyObjectResult>")
+		case req.Method == "DELETE":
+			delete(objects, key)
+			return testResponse(204, nil, "")
+		}
+		return testResponse(400, nil, "")
+	})
+}
+
+func TestMovePrefixKeys(t *testing.T) {
+	objects := map[string]string{"src/dir/": "d", "src/ x ": "x"}
+	c := &Client{Endpoint: "http://s3.test", HTTPClient: &http.Client{Transport: fakeMoveTransport(objects, false)}}
+	report, err := c.Bucket("b").MovePrefix("src/", "dst/")
+	if err != nil {
+		t.Fatal(err, report.Failed)
+	}
+	if len(objects) != 2 || objects["dst/dir/"] != "d" || objects["dst/ x "] != "x" {
+		t.Fatal(objects)
+	}
+
+	objects = map[string]string{"src/a": "a"}
+	c.HTTPClient.Transport = fakeMoveTransport(objects, true)
+	if _, err := c.Bucket("b").MovePrefix("src/", "dst/"); err == nil {
+		t.Fatal("tampered copy moved")
+	}
+	if objects["src/a"] != "a" {
+		t.Fatal(objects)
+	}
+}
+
+func TestComposeDownloadIfMatch(t *testing.T) {
+	c := &Client{Endpoint: "http://s3.test", HTTPClient: &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) *http.Response {
+			if req.Header.Get("If-Match") != `"old"` {
+				return testResponse(400, nil, "")
+			}
+			return testResponse(412, nil, "<Error><Code>PreconditionFailed</Code></Error>")
+		}),
+	}}
+	var buf strings.Builder
+	err := download(&buf, c.Bucket("b").raw("a"), ReadOptions{Length: 1, IfMatch: `"old"`})
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatal(err)
+	}
+}
+
+func TestCopierAbortsFailedComplete(t *testing.T) {
+	var m sync.Mutex
+	var aborted []string
+	c := &Client{Endpoint: "http://s3.test", HTTPClient: &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) *http.Response {
+			q := req.URL.Query()
+			switch {
+			case req.Method == "HEAD":
+				return testResponse(200, http.Header{
+					"Etag":           {`"src"`},
+					"Content-Length": {fmt.Sprint(MaxCopySize + 1)},
+				}, "")
+			case req.Method == "POST" && q.Get("uploadId") == "":
+				return testResponse(200, nil, "<InitiateMultipartUploadResult><UploadId>up</UploadId></InitiateMultipartUploadResult>")
+			case req.Method == "PUT":
+				return testResponse(200, nil, `<CopyPartResult><ETag>"part"</ETag></CopyPartResult>`)
+			case req.Method == "POST":
+				return testResponse(500, nil, "<Error><Code>InternalError</Code></Error>")
+			case req.Method == "DELETE":
+				m.Lock()
+				aborted = append(aborted, req.URL.Path+"?uploadId="+q.Get("uploadId"))
+				m.Unlock()
+				return testResponse(204, nil, "")
+			}
+			return testResponse(400, nil, "")
+		}),
+	}}
+	s3 := c.Bucket("b")
+	_, err := new(Copier).Copy(s3.Object("src"), s3.Object("dst"))
+	if ErrorCode(err) != "InternalError" {
+		t.Fatal(err)
+	}
+	if x := strings.Join(aborted, ","); x != "/b/dst?uploadId=up" {
+		t.Fatal(x)
+	}
+}
+
+func TestComposeSourceEncryption(t *testing.T) {
+	key := &Encryption{CustomerKey: []byte("0123456789abcdef0123456789abcdef")}
+	sizes := map[string]int64{"/b/big": 2 * MinPartSize, "/b/small": 1}
+
+	var m sync.Mutex
+	var reqs []string
+	c := &Client{Endpoint: "http://s3.test", HTTPClient: &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) *http.Response {
+			m.Lock()
+			defer m.Unlock()
+			reqs = append(reqs, req.Method+" "+req.URL.Path)
+
+			h := req.Header
+			q := req.URL.Query()
+			switch {
+			case req.Method == "HEAD" || req.Method == "GET":
+				if h.Get("x-amz-server-side-encryption-customer-key") == "" {
+					return testResponse(400, nil, "<Error><Code>InvalidRequest</Code></Error>")
+				}
+				if req.Method == "GET" && h.Get("If-Match") != `"etag"` {
+					return testResponse(412, nil, "")
+				}
+				if req.Method == "GET" {
+					return testResponse(206, nil, "x")
+				}
+				n := sizes[req.URL.Path]
+				return testResponse(200, http.Header{"Etag": {`"etag"`}, "Content-Length": {fmt.Sprint(n)}}, "")
+			case req.Method == "POST" && q.Get("uploadId") == "":
+				return testResponse(200, nil, "<InitiateMultipartUploadResult><UploadId>up</UploadId></InitiateMultipartUploadResult>")
+			case req.Method == "PUT" && h.Get("x-amz-copy-source") != "":
+				if h.Get("x-amz-copy-source-server-side-encryption-customer-key") == "" {
+					return testResponse(400, nil, "<Error><Code>InvalidRequest</Code></Error>")
+				}
+				return testResponse(200, nil, `<CopyPartResult><ETag>"part"</ETag></CopyPartResult>`)
+			case req.Method == "PUT":
+				return testResponse(200, http.Header{"Etag": {`"part"`}}, "")
+			case req.Method == "POST":
+				return testResponse(200, nil, `<CompleteMultipartUploadResult><ETag>"all"</ETag></CompleteMultipartUploadResult>`)
+			}
+			return testResponse(400, nil, "")
+		}),
+	}}
+	s3 := c.Bucket("b")
+	res, err := new(Copier).Compose(s3.Object("dst"), []Object{s3.Object("big"), s3.Object("small")},
+		ComposeOptions{SourceEncryption: []*Encryption{key, key}})
+	if err != nil {
+		t.Fatal(err, reqs)
+	}
+	if res.ETag != "all" {
+		t.Fatal(res.ETag)
+	}
+	if x := strings.Join(reqs, ","); x != "HEAD /b/big,HEAD /b/small,POST /b/dst,GET /b/small,PUT /b/dst,PUT /b/dst,POST /b/dst" {
+		t.Fatal(x)
+	}
+}
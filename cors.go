@@ -0,0 +1,137 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"strings"
+)
+
+// CORSConfiguration holds the CORS rules of a bucket
+type CORSConfiguration struct {
+	XMLName xml.Name   `xml:"CORSConfiguration"`
+	Rules   []CORSRule `xml:"CORSRule"`
+}
+
+// CORSRule allows cross origin requests. Origins and headers may contain one
+// * wildcard, headers are matched case insensitively.
+type CORSRule struct {
+	ID             string   `xml:",omitempty"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedHeaders []string `xml:"AllowedHeader"`
+
+	// ExposeHeaders are the response headers browsers make accessible
+	ExposeHeaders []string `xml:"ExposeHeader"`
+
+	// MaxAgeSeconds is how long browsers cache the preflight response
+	MaxAgeSeconds int `xml:",omitempty"`
+}
+
+// Match returns the rule that applies to a request from origin with method
+// and, for preflight requests, the headers listed in
+// Access-Control-Request-Headers. Like S3, it uses the first rule that
+// allows the request. It returns nil if the request isn't allowed.
+func (c *CORSConfiguration) Match(origin, method string, headers ...string) *CORSRule {
+	for i := range c.Rules {
+		if r := &c.Rules[i]; r.allows(origin, method, headers) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (r *CORSRule) allows(origin, method string, headers []string) bool {
+	if !matchAny(r.AllowedOrigins, origin, false) || !matchAny(r.AllowedMethods, method, false) {
+		return false
+	}
+	for _, h := range headers {
+		if !matchAny(r.AllowedHeaders, h, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAny reports whether s matches any of the wildcard patterns
+func matchAny(patterns []string, s string, fold bool) bool {
+	if fold {
+		s = strings.ToLower(s)
+	}
+	for _, p := range patterns {
+		if fold {
+			p = strings.ToLower(p)
+		}
+		if wildcardMatch(p, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardMatch matches s against a pattern with at most one *
+func wildcardMatch(pattern, s string) bool {
+	i := strings.IndexByte(pattern, '*')
+	if i < 0 {
+		return pattern == s
+	}
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	return len(s) >= len(prefix)+len(suffix) && strings.HasPrefix(s, prefix) && strings.HasSuffix(s, suffix)
+}
+
+// GetCORS returns the CORS configuration of the bucket. A bucket without one
+// returns an empty configuration.
+func (s3 *S3) GetCORS() (*CORSConfiguration, error) {
+	req, err := http.NewRequest("GET", s3.bucketURL("?cors"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s3.do(req, 200, "error getting cors")
+	if ErrorCode(err) == "NoSuchCORSConfiguration" {
+		return new(CORSConfiguration), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	cc := new(CORSConfiguration)
+	if err := xml.NewDecoder(resp.Body).Decode(cc); err != nil {
+		return nil, err
+	}
+	return cc, nil
+}
+
+// PutCORS replaces the CORS configuration of the bucket
+func (s3 *S3) PutCORS(cc *CORSConfiguration) error {
+	b, err := xml.Marshal(cc)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("PUT", s3.bucketURL("?cors"), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-MD5", contentMD5(b))
+
+	resp, err := s3.do(req, 200, "error putting cors")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DeleteCORS removes all CORS rules of the bucket
+func (s3 *S3) DeleteCORS() error {
+	req, err := http.NewRequest("DELETE", s3.bucketURL("?cors"), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s3.do(req, 204, "error deleting cors")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
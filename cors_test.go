@@ -0,0 +1,81 @@
+package s3
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+const corsXML = `<?xml version="1.0" encoding="UTF-8"?>
+<CORSConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <CORSRule>
+    <ID>uploads</ID>
+    <AllowedOrigin>https://*.example.com</AllowedOrigin>
+    <AllowedMethod>PUT</AllowedMethod>
+    <AllowedMethod>POST</AllowedMethod>
+    <AllowedHeader>Content-*</AllowedHeader>
+    <AllowedHeader>x-amz-acl</AllowedHeader>
+    <ExposeHeader>ETag</ExposeHeader>
+    <MaxAgeSeconds>3000</MaxAgeSeconds>
+  </CORSRule>
+  <CORSRule>
+    <AllowedOrigin>*</AllowedOrigin>
+    <AllowedMethod>GET</AllowedMethod>
+  </CORSRule>
+</CORSConfiguration>`
+
+func TestCORSConfiguration(t *testing.T) {
+	var cc CORSConfiguration
+	if err := xml.Unmarshal([]byte(corsXML), &cc); err != nil {
+		t.Fatal(err)
+	}
+	if x := len(cc.Rules); x != 2 {
+		t.Fatal(x)
+	}
+	r := cc.Rules[0]
+	if r.ID != "uploads" || len(r.AllowedMethods) != 2 || len(r.AllowedHeaders) != 2 || r.ExposeHeaders[0] != "ETag" || r.MaxAgeSeconds != 3000 {
+		t.Fatal(r)
+	}
+
+	for _, c := range []struct {
+		origin, method string
+		headers        []string
+		rule           int
+	}{
+		{"https://www.example.com", "PUT", nil, 0},
+		{"https://www.example.com", "POST", []string{"content-type", "X-Amz-Acl"}, 0},
+		{"https://www.example.com", "PUT", []string{"x-amz-meta-a"}, -1},
+		{"https://example.com", "PUT", nil, -1},
+		{"http://www.example.com", "PUT", nil, -1},
+		{"https://www.example.com", "GET", nil, 1},
+		{"https://other.org", "GET", nil, 1},
+		{"https://other.org", "GET", []string{"Range"}, -1},
+		{"https://other.org", "DELETE", nil, -1},
+	} {
+		r := cc.Match(c.origin, c.method, c.headers...)
+		switch {
+		case c.rule < 0 && r != nil:
+			t.Fatal(c, r)
+		case c.rule >= 0 && r != &cc.Rules[c.rule]:
+			t.Fatal(c, r)
+		}
+	}
+}
+
+func TestWildcardMatch(t *testing.T) {
+	for _, c := range []struct {
+		pattern, s string
+		match      bool
+	}{
+		{"*", "", true},
+		{"*", "abc", true},
+		{"a*c", "abc", true},
+		{"a*c", "ac", true},
+		{"ab*bc", "abc", false},
+		{"abc", "abc", true},
+		{"abc", "abcd", false},
+	} {
+		if x := wildcardMatch(c.pattern, c.s); x != c.match {
+			t.Fatal(c)
+		}
+	}
+}
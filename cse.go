@@ -0,0 +1,493 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// Client side encryption stores objects as a sequence of AES-256-GCM sealed
+// chunks. Each object gets a random data key, which is wrapped by a KeyWrapper
+// and stored in the object metadata together with the chunk size.
+//
+// Chunk i is sealed with a nonce holding i as a big endian counter and a flag
+// marking the final chunk, so reordered, dropped or truncated chunks fail to
+// authenticate. The final chunk may be shorter than the chunk size or empty.
+
+const (
+	cseCipher           = "AES-256-GCM-CHUNKED"
+	cseDefaultChunkSize = 64 * 1024
+
+	cseMetaCipher    = "cse-cipher"
+	cseMetaKey       = "cse-key"
+	cseMetaChunkSize = "cse-chunk-size"
+
+	// the compression is applied before encrypting, so it isn't recorded in
+	// the Content-Encoding header
+	cseMetaCompression = "cse-compression"
+)
+
+// ErrNotEncrypted is returned when reading an object through an encrypted
+// object handle that wasn't written with client side encryption.
+var ErrNotEncrypted = errors.New("s3: object is not client side encrypted")
+
+// KeyWrapper encrypts and decrypts the per object data keys, e.g. with a key
+// encryption key held in a KMS or HSM.
+type KeyWrapper interface {
+	WrapKey(key []byte) ([]byte, error)
+	UnwrapKey(wrapped []byte) ([]byte, error)
+}
+
+// NewAESKeyWrapper returns a KeyWrapper sealing data keys with AES-GCM under
+// the given 256 bit key encryption key.
+func NewAESKeyWrapper(kek []byte) (KeyWrapper, error) {
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	return &aesKeyWrapper{aead}, nil
+}
+
+type aesKeyWrapper struct {
+	aead cipher.AEAD
+}
+
+func (kw *aesKeyWrapper) WrapKey(key []byte) ([]byte, error) {
+	nonce := make([]byte, kw.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return kw.aead.Seal(nonce, nonce, key, nil), nil
+}
+
+func (kw *aesKeyWrapper) UnwrapKey(wrapped []byte) ([]byte, error) {
+	n := kw.aead.NonceSize()
+	if len(wrapped) < n {
+		return nil, errors.New("s3: wrapped key too short")
+	}
+	return kw.aead.Open(nil, wrapped[:n], wrapped[n:], nil)
+}
+
+// EncryptedObject returns an object handle that encrypts everything written
+// with Writer or Put and decrypts and verifies everything read with Reader.
+// Ranged reads are served by fetching only the chunks covering the range. All
+// other methods operate on the stored ciphertext.
+func EncryptedObject(o Object, kw KeyWrapper) Object {
+	return &encryptedObject{Object: o, kw: kw, chunkSize: cseDefaultChunkSize}
+}
+
+type encryptedObject struct {
+	Object
+	kw        KeyWrapper
+	chunkSize int
+}
+
+// seal prepares the write options and returns the cipher for a new data key
+func (e *encryptedObject) seal(opts []WriteOptions) (WriteOptions, cipher.AEAD, error) {
+	wo := writeOptions(opts)
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return wo, nil, err
+	}
+	wrapped, err := e.kw.WrapKey(key)
+	if err != nil {
+		return wo, nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return wo, nil, err
+	}
+
+	meta := make(map[string]string, len(wo.Metadata)+4)
+	for k, v := range wo.Metadata {
+		meta[k] = v
+	}
+	meta[cseMetaCipher] = cseCipher
+	meta[cseMetaKey] = base64.StdEncoding.EncodeToString(wrapped)
+	meta[cseMetaChunkSize] = strconv.Itoa(e.chunkSize)
+	if wo.Compression != "" {
+		meta[cseMetaCompression] = string(wo.Compression)
+		wo.Compression = ""
+	}
+	wo.Metadata = meta
+
+	return wo, aead, nil
+}
+
+func (e *encryptedObject) Writer(opts ...WriteOptions) Writer {
+	wo, aead, err := e.seal(opts)
+	if err != nil {
+		return &encryptWriter{err: err}
+	}
+	w := e.Object.Writer(wo)
+	ew := &encryptWriter{
+		chunkEncrypter: newChunkEncrypter(w, aead, e.chunkSize),
+		w:              w,
+	}
+	if c := Compression(wo.Metadata[cseMetaCompression]); c != "" {
+		return newCompressWriter(ew, c)
+	}
+	return ew
+}
+
+func (e *encryptedObject) Put(r io.Reader, opts ...WriteOptions) (Header, error) {
+	wo, aead, err := e.seal(opts)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	ce := newChunkEncrypter(&buf, aead, e.chunkSize)
+	if c := Compression(wo.Metadata[cseMetaCompression]); c != "" {
+		zw, err := compress(ce, c)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(zw, r); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	} else if _, err := io.Copy(ce, r); err != nil {
+		return nil, err
+	}
+	if err := ce.finish(); err != nil {
+		return nil, err
+	}
+	return e.Object.Put(&buf, wo)
+}
+
+func (e *encryptedObject) Reader(opts ...ReadOptions) (io.ReadCloser, http.Header, error) {
+	ro := readOptions(opts)
+	decompress := ro.Decompress
+	ro.Decompress = false
+
+	r, h, err := e.read(ro)
+	if err != nil || !decompress {
+		return r, h, err
+	}
+	c := Compression(Header(h).Metadata()[cseMetaCompression])
+	if c == "" {
+		return r, h, nil
+	}
+	if ro.ranged() {
+		r.Close()
+		return nil, nil, errCompressedRange
+	}
+	zr, err := decompressStream(r, c)
+	if err != nil {
+		r.Close()
+		return nil, nil, err
+	}
+	return zr, h, nil
+}
+
+// read returns a reader for the decrypted object data
+func (e *encryptedObject) read(ro ReadOptions) (io.ReadCloser, http.Header, error) {
+	if !ro.ranged() {
+		r, h, err := e.Object.Reader(ro)
+		if err != nil {
+			return nil, nil, err
+		}
+		ctLen, err := Header(h).ContentLength()
+		if err != nil {
+			r.Close()
+			return nil, nil, err
+		}
+		d, err := e.decrypter(r, Header(h), ctLen, 0, 0, -1)
+		if err != nil {
+			r.Close()
+			return nil, nil, err
+		}
+		return d, h, nil
+	}
+
+	// ranged reads need the chunk size and the total size up front
+	h, err := e.Object.Head(ro)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctLen, err := h.ContentLength()
+	if err != nil {
+		return nil, nil, err
+	}
+	chunkSize, err := cseChunkSize(h)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	n := cseChunks(ctLen, chunkSize)
+	plainLen := ctLen - int64(n)*int64(aesOverhead)
+	end := plainLen
+	if ro.Length > 0 && ro.Offset+ro.Length < end {
+		end = ro.Offset + ro.Length
+	}
+	if ro.Offset >= end {
+		return ioutil.NopCloser(bytes.NewReader(nil)), http.Header(h), nil
+	}
+
+	first := ro.Offset / int64(chunkSize)
+	last := (end - 1) / int64(chunkSize)
+	sealedSize := int64(chunkSize + aesOverhead)
+
+	// the chunks must belong to the object the metadata was read from
+	cro := ro
+	if cro.IfMatch == "" {
+		cro.IfMatch = h.ETag()
+	}
+	cro.Offset = first * sealedSize
+	cro.Length = (last+1)*sealedSize - cro.Offset
+	if cro.Offset+cro.Length > ctLen {
+		cro.Length = ctLen - cro.Offset
+	}
+
+	r, rh, err := e.Object.Reader(cro)
+	if err != nil {
+		return nil, nil, err
+	}
+	d, err := e.decrypter(r, h, ctLen, first, ro.Offset-first*int64(chunkSize), end-ro.Offset)
+	if err != nil {
+		r.Close()
+		return nil, nil, err
+	}
+	return d, rh, nil
+}
+
+// decrypter returns a reader decrypting r, which starts at chunk first of an
+// object with ctLen bytes of ciphertext. The first skip bytes of plaintext are
+// discarded and at most limit bytes are returned, unless limit is negative.
+func (e *encryptedObject) decrypter(r io.ReadCloser, h Header, ctLen, first, skip, limit int64) (io.ReadCloser, error) {
+	meta := h.Metadata()
+	if meta[cseMetaCipher] != cseCipher {
+		return nil, ErrNotEncrypted
+	}
+	chunkSize, err := cseChunkSize(h)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(meta[cseMetaKey])
+	if err != nil {
+		return nil, err
+	}
+	key, err := e.kw.UnwrapKey(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	d := newChunkDecrypter(r, aead, chunkSize, ctLen, uint64(first))
+	d.skip = skip
+	d.limit = limit
+	return d, nil
+}
+
+func cseChunkSize(h Header) (int, error) {
+	n, err := strconv.Atoi(h.Metadata()[cseMetaChunkSize])
+	if err != nil || n <= 0 {
+		return 0, ErrNotEncrypted
+	}
+	return n, nil
+}
+
+// cseChunks returns the number of sealed chunks in ctLen bytes of ciphertext
+func cseChunks(ctLen int64, chunkSize int) uint64 {
+	sealedSize := int64(chunkSize + aesOverhead)
+	return uint64((ctLen + sealedSize - 1) / sealedSize)
+}
+
+const aesOverhead = 16
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce of chunk i
+func chunkNonce(i uint64, final bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[4:], i)
+	if final {
+		nonce[0] = 1
+	}
+	return nonce
+}
+
+type chunkEncrypter struct {
+	w         io.Writer
+	aead      cipher.AEAD
+	chunkSize int
+	buf       []byte
+	n         uint64
+}
+
+func newChunkEncrypter(w io.Writer, aead cipher.AEAD, chunkSize int) *chunkEncrypter {
+	return &chunkEncrypter{
+		w:         w,
+		aead:      aead,
+		chunkSize: chunkSize,
+		buf:       make([]byte, 0, chunkSize+aesOverhead),
+	}
+}
+
+func (ce *chunkEncrypter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		// a full chunk is only sealed once more data follows, since the final
+		// chunk is sealed differently
+		if len(ce.buf) == ce.chunkSize {
+			if err := ce.seal(false); err != nil {
+				return n - len(p), err
+			}
+		}
+		m := ce.chunkSize - len(ce.buf)
+		if m > len(p) {
+			m = len(p)
+		}
+		ce.buf = append(ce.buf, p[:m]...)
+		p = p[m:]
+	}
+	return n, nil
+}
+
+// finish seals the final chunk
+func (ce *chunkEncrypter) finish() error {
+	return ce.seal(true)
+}
+
+func (ce *chunkEncrypter) seal(final bool) error {
+	b := ce.aead.Seal(ce.buf[:0], chunkNonce(ce.n, final), ce.buf, nil)
+	ce.n++
+	ce.buf = ce.buf[:0]
+	_, err := ce.w.Write(b)
+	return err
+}
+
+type encryptWriter struct {
+	*chunkEncrypter
+	w   Writer
+	err error
+}
+
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+	return ew.chunkEncrypter.Write(p)
+}
+
+func (ew *encryptWriter) Close() error {
+	if ew.err != nil {
+		return ew.err
+	}
+	if err := ew.finish(); err != nil {
+		ew.w.Abort()
+		return err
+	}
+	return ew.w.Close()
+}
+
+func (ew *encryptWriter) Abort() error {
+	if ew.err != nil {
+		return ew.err
+	}
+	return ew.w.Abort()
+}
+
+type chunkDecrypter struct {
+	r         io.ReadCloser
+	aead      cipher.AEAD
+	chunkSize int
+	ctLen     int64
+	n         uint64
+	last      uint64
+	buf       []byte
+	plain     []byte
+	skip      int64
+	limit     int64
+}
+
+func newChunkDecrypter(r io.ReadCloser, aead cipher.AEAD, chunkSize int, ctLen int64, first uint64) *chunkDecrypter {
+	n := cseChunks(ctLen, chunkSize)
+	if n == 0 {
+		// not even the final chunk, which is always written
+		n = 1
+	}
+	return &chunkDecrypter{
+		r:         r,
+		aead:      aead,
+		chunkSize: chunkSize,
+		ctLen:     ctLen,
+		n:         first,
+		last:      n - 1,
+		buf:       make([]byte, chunkSize+aesOverhead),
+		limit:     -1,
+	}
+}
+
+func (cd *chunkDecrypter) Read(p []byte) (int, error) {
+	for len(cd.plain) == 0 {
+		if cd.limit == 0 || cd.n > cd.last {
+			return 0, io.EOF
+		}
+		if err := cd.open(); err != nil {
+			return 0, err
+		}
+	}
+	if cd.limit >= 0 && int64(len(cd.plain)) > cd.limit {
+		cd.plain = cd.plain[:cd.limit]
+	}
+	n := copy(p, cd.plain)
+	cd.plain = cd.plain[n:]
+	if cd.limit > 0 {
+		cd.limit -= int64(n)
+	}
+	return n, nil
+}
+
+// open reads and decrypts the next chunk
+func (cd *chunkDecrypter) open() error {
+	size := int64(len(cd.buf))
+	if rest := cd.ctLen - int64(cd.n)*size; rest < size {
+		size = rest
+	}
+	if size < aesOverhead {
+		return io.ErrUnexpectedEOF
+	}
+	b := cd.buf[:size]
+	if _, err := io.ReadFull(cd.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	plain, err := cd.aead.Open(b[:0], chunkNonce(cd.n, cd.n == cd.last), b, nil)
+	if err != nil {
+		return err
+	}
+	cd.n++
+
+	if cd.skip > 0 {
+		plain = plain[cd.skip:]
+		cd.skip = 0
+	}
+	cd.plain = plain
+	return nil
+}
+
+func (cd *chunkDecrypter) Close() error {
+	return cd.r.Close()
+}
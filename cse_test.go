@@ -0,0 +1,179 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestAESKeyWrapper(t *testing.T) {
+	kek := make([]byte, 32)
+	rand.Read(kek)
+	kw, err := NewAESKeyWrapper(kek)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	wrapped, err := kw.WrapKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(wrapped, key) {
+		t.Fatal("key not wrapped")
+	}
+	unwrapped, err := kw.UnwrapKey(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unwrapped, key) {
+		t.Fatal(unwrapped)
+	}
+
+	wrapped[len(wrapped)-1] ^= 1
+	if _, err := kw.UnwrapKey(wrapped); err == nil {
+		t.Fatal("tampered key unwrapped")
+	}
+}
+
+func TestChunkEncryption(t *testing.T) {
+	const chunkSize = 16
+	aead, err := newGCM(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []int{0, 1, 15, 16, 17, 32, 100} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		var ct bytes.Buffer
+		ce := newChunkEncrypter(&ct, aead, chunkSize)
+		// write in odd sized pieces
+		for p := plain; len(p) > 0; {
+			n := 7
+			if n > len(p) {
+				n = len(p)
+			}
+			ce.Write(p[:n])
+			p = p[n:]
+		}
+		if err := ce.finish(); err != nil {
+			t.Fatal(err)
+		}
+
+		// the final chunk is always written
+		nChunks := (size + chunkSize - 1) / chunkSize
+		if nChunks == 0 {
+			nChunks = 1
+		}
+		if x := ct.Len(); x != size+nChunks*aesOverhead {
+			t.Fatal(size, x)
+		}
+		if x := cseChunks(int64(ct.Len()), chunkSize); x != uint64(nChunks) {
+			t.Fatal(size, x)
+		}
+
+		// full read
+		cd := newChunkDecrypter(ioutil.NopCloser(bytes.NewReader(ct.Bytes())), aead, chunkSize, int64(ct.Len()), 0)
+		b, err := ioutil.ReadAll(cd)
+		if err != nil {
+			t.Fatal(size, err)
+		}
+		if !bytes.Equal(b, plain) {
+			t.Fatal(size, b)
+		}
+
+		// ranged reads starting at a chunk boundary of the ciphertext
+		for off := 0; off < size; off += 5 {
+			for _, n := range []int{1, 9, 16, 40} {
+				end := off + n
+				if end > size {
+					end = size
+				}
+				first := off / chunkSize
+				sealed := ct.Bytes()[first*(chunkSize+aesOverhead):]
+				cd := newChunkDecrypter(ioutil.NopCloser(bytes.NewReader(sealed)), aead, chunkSize, int64(ct.Len()), uint64(first))
+				cd.skip = int64(off - first*chunkSize)
+				cd.limit = int64(end - off)
+				b, err := ioutil.ReadAll(cd)
+				if err != nil {
+					t.Fatal(size, off, n, err)
+				}
+				if !bytes.Equal(b, plain[off:end]) {
+					t.Fatal(size, off, n, b)
+				}
+			}
+		}
+	}
+}
+
+func TestChunkEncryptionTamper(t *testing.T) {
+	const chunkSize = 16
+	aead, _ := newGCM(make([]byte, 32))
+
+	var ct bytes.Buffer
+	ce := newChunkEncrypter(&ct, aead, chunkSize)
+	ce.Write(make([]byte, 40))
+	ce.finish()
+
+	read := func(b []byte) error {
+		cd := newChunkDecrypter(ioutil.NopCloser(bytes.NewReader(b)), aead, chunkSize, int64(len(b)), 0)
+		_, err := ioutil.ReadAll(cd)
+		return err
+	}
+	if err := read(ct.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	// flipped bit
+	b := append([]byte(nil), ct.Bytes()...)
+	b[20] ^= 1
+	if err := read(b); err == nil {
+		t.Fatal("tampered chunk accepted")
+	}
+
+	// truncated at a chunk boundary, the last chunk isn't marked final
+	if err := read(ct.Bytes()[:2*(chunkSize+aesOverhead)]); err == nil {
+		t.Fatal("truncated stream accepted")
+	}
+
+	// swapped chunks
+	b = append([]byte(nil), ct.Bytes()...)
+	n := chunkSize + aesOverhead
+	copy(b[:n], ct.Bytes()[n:2*n])
+	copy(b[n:2*n], ct.Bytes()[:n])
+	if err := read(b); err == nil {
+		t.Fatal("reordered chunks accepted")
+	}
+}
+
+func TestEncryptedRangeIfMatch(t *testing.T) {
+	var ifMatch string
+	c := &Client{Endpoint: "http://s3.test", HTTPClient: &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) *http.Response {
+			if req.Method == "HEAD" {
+				return testResponse(200, http.Header{
+					"Etag":                      {`"v1"`},
+					"Content-Length":            {"100"},
+					"X-Amz-Meta-Cse-Chunk-Size": {"16"},
+				}, "")
+			}
+			// the object was replaced after the HEAD
+			ifMatch = req.Header.Get("If-Match")
+			return testResponse(412, nil, "<Error><Code>PreconditionFailed</Code></Error>")
+		}),
+	}}
+	kw, err := NewAESKeyWrapper(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := EncryptedObject(c.Bucket("b").Object("a"), kw)
+	_, _, err = o.Reader(ReadOptions{Offset: 20, Length: 10})
+	if !errors.Is(err, ErrPreconditionFailed) || ifMatch != `"v1"` {
+		t.Fatal(err, ifMatch)
+	}
+}
@@ -0,0 +1,252 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// errDeleteStopped ends the listing of DeletePrefix after a failed request
+var errDeleteStopped = errors.New("s3: delete stopped")
+
+// MaxDeleteObjects is the number of keys S3 deletes with one request
+const MaxDeleteObjects = 1000
+
+// DeleteError is the failure to delete a single key of a batch delete
+type DeleteError struct {
+	Key       string
+	VersionID string `xml:"VersionId"`
+	Code      string
+	Message   string
+}
+
+func (e *DeleteError) Error() string {
+	return "s3: error deleting " + e.Key + " (" + e.Code + ": " + e.Message + ")"
+}
+
+// deleteObject is an object in a multi object delete request
+type deleteObject struct {
+	Key       string
+	VersionID string `xml:"VersionId,omitempty"`
+}
+
+// DeleteObjects deletes the keys, which are relative to Path, with multi object
+// delete requests of up to MaxDeleteObjects keys. Keys that couldn't be deleted
+// are returned with the reason. The error is set if a request failed as a
+// whole, in which case the remaining keys weren't deleted.
+func (s3 *S3) DeleteObjects(keys []string) ([]DeleteError, error) {
+	objs := make([]deleteObject, len(keys))
+	for i, k := range keys {
+		objs[i].Key = s3.prefix(k)
+	}
+
+	var failed []DeleteError
+	for len(objs) > 0 {
+		n := len(objs)
+		if n > MaxDeleteObjects {
+			n = MaxDeleteObjects
+		}
+		errs, err := s3.deleteObjects(objs[:n])
+		failed = append(failed, s3.relErrors(errs)...)
+		if err != nil {
+			return failed, err
+		}
+		objs = objs[n:]
+	}
+	return failed, nil
+}
+
+// deleteObjects sends a single quiet multi object delete request. The keys of
+// the returned errors are bucket keys as returned by S3.
+func (s3 *S3) deleteObjects(objs []deleteObject) ([]DeleteError, error) {
+	var v struct {
+		XMLName xml.Name `xml:"Delete"`
+		Quiet   bool
+		Object  []deleteObject
+	}
+	v.Quiet = true
+	v.Object = objs
+
+	b, err := xml.Marshal(&v)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", s3.bucketURL("?delete"), bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-MD5", contentMD5(b))
+
+	resp, err := s3.do(req, 200, "error deleting objects")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		Error []DeleteError
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return res.Error, nil
+}
+
+// relErrors makes the keys of errs relative to Path
+func (s3 *S3) relErrors(errs []DeleteError) []DeleteError {
+	for i := range errs {
+		errs[i].Key = s3.relKey(errs[i].Key)
+	}
+	return errs
+}
+
+// DeletePrefixOptions configure DeletePrefix
+type DeletePrefixOptions struct {
+	// Versions deletes all versions and delete markers of the keys, removing
+	// them permanently from a versioned bucket. Otherwise only the current
+	// versions are deleted, which leaves delete markers in versioned buckets.
+	Versions bool
+
+	// DryRun only lists what would be deleted
+	DryRun bool
+
+	// Concurrency is the number of delete requests sent at once. Defaults to 5.
+	Concurrency int
+}
+
+// DeleteReport summarizes a DeletePrefix
+type DeleteReport struct {
+	// Deleted is the number of deleted keys, or versions if deleting versions
+	Deleted int
+	Bytes   int64
+
+	// Failed holds the keys that couldn't be deleted
+	Failed []DeleteError
+}
+
+// deleteBatch is a multi object delete request and the sizes of its objects
+type deleteBatch struct {
+	objs  []deleteObject
+	sizes []int64
+}
+
+// DeletePrefix deletes all keys with the prefix, which is relative to Path. An
+// empty prefix deletes everything below Path. The listing is streamed into
+// batched multi object deletes. The first failed delete request stops the
+// listing and no further batches are sent. The returned error is set if listing
+// or a delete request failed, or any key couldn't be deleted, in which case the
+// report holds the failures.
+func (s3 *S3) DeletePrefix(prefix string, opts ...DeletePrefixOptions) (*DeleteReport, error) {
+	var do DeletePrefixOptions
+	if len(opts) > 0 {
+		do = opts[0]
+	}
+	concurrency := do.Concurrency
+	if concurrency <= 0 {
+		concurrency = nConcurrentUploads
+	}
+
+	var (
+		wg      sync.WaitGroup
+		m       sync.Mutex
+		report  = new(DeleteReport)
+		reqErr  error
+		batches = make(chan *deleteBatch)
+		stop    = make(chan struct{})
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches {
+				select {
+				case <-stop:
+					continue
+				default:
+				}
+
+				var errs []DeleteError
+				var err error
+				if !do.DryRun {
+					errs, err = s3.deleteObjects(b.objs)
+				}
+
+				// listed keys are exact, so they match the errors as is
+				failed := make(map[deleteObject]bool)
+				for _, e := range errs {
+					failed[deleteObject{e.Key, e.VersionID}] = true
+				}
+
+				m.Lock()
+				if err != nil {
+					if reqErr == nil {
+						reqErr = err
+						close(stop)
+					}
+				} else {
+					for i, o := range b.objs {
+						if !failed[o] {
+							report.Deleted++
+							report.Bytes += b.sizes[i]
+						}
+					}
+					report.Failed = append(report.Failed, s3.relErrors(errs)...)
+				}
+				m.Unlock()
+			}
+		}()
+	}
+
+	b := new(deleteBatch)
+	send := func() error {
+		select {
+		case batches <- b:
+		case <-stop:
+			return errDeleteStopped
+		}
+		b = new(deleteBatch)
+		return nil
+	}
+	add := func(o deleteObject, size int64) error {
+		b.objs = append(b.objs, o)
+		b.sizes = append(b.sizes, size)
+		if len(b.objs) == MaxDeleteObjects {
+			return send()
+		}
+		return nil
+	}
+
+	var err error
+	root := s3.prefix(prefix)
+	if do.Versions {
+		err = s3.listAllVersions(root, func(v ObjectVersion) error {
+			return add(deleteObject{v.Key, v.VersionID}, v.Size)
+		})
+	} else {
+		err = s3.listKeys(root, func(key string, size int64) error {
+			return add(deleteObject{Key: key}, size)
+		})
+	}
+	if err == nil && len(b.objs) > 0 {
+		err = send()
+	}
+	close(batches)
+	wg.Wait()
+
+	if err == errDeleteStopped {
+		err = nil
+	}
+	if err == nil {
+		err = reqErr
+	}
+	if err != nil {
+		return report, err
+	}
+	if n := len(report.Failed); n > 0 {
+		return report, fmt.Errorf("s3: failed to delete %d of %d objects", n, n+report.Deleted)
+	}
+	return report, nil
+}
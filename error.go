@@ -0,0 +1,89 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrPreconditionFailed is returned when the condition of a conditional
+	// request did not hold, e.g. If-None-Match: * on an existing key or an
+	// If-Match ETag that is no longer current.
+	ErrPreconditionFailed = errors.New("s3: precondition failed")
+
+	// ErrConflict is returned when a concurrent operation on the same key
+	// conflicted with a conditional write, which may be retried. Other 409
+	// errors like BucketNotEmpty don't match it, see ErrorCode.
+	ErrConflict = errors.New("s3: conflicting operation")
+)
+
+type s3err struct {
+	code    int
+	text    string
+	xmlBody string
+	header  http.Header
+}
+
+func newS3Error(resp *http.Response, strFmt string, args ...interface{}) *s3err {
+	var b bytes.Buffer
+	if resp != nil {
+		b.ReadFrom(resp.Body)
+	}
+	return &s3err{
+		code:    resp.StatusCode,
+		text:    fmt.Sprintf(strFmt, args...),
+		xmlBody: b.String(),
+		header:  resp.Header,
+	}
+}
+
+func (e *s3err) Error() string {
+	return e.text
+}
+
+// Unwrap maps status codes with a well defined meaning to the exported errors,
+// so they can be tested with errors.Is.
+func (e *s3err) Unwrap() error {
+	switch e.code {
+	case http.StatusPreconditionFailed:
+		return ErrPreconditionFailed
+	case http.StatusConflict:
+		if e.xmlCode() == "ConditionalRequestConflict" {
+			return ErrConflict
+		}
+	}
+	return nil
+}
+
+// ErrorHeader returns the response header of a failed S3 request, or nil if err
+// isn't from a response. Reading a delete marker fails, and the header tells
+// so with DeleteMarker and VersionID.
+func ErrorHeader(err error) Header {
+	var e *s3err
+	if !errors.As(err, &e) {
+		return nil
+	}
+	return Header(e.header)
+}
+
+// ErrorCode returns the code of the S3 error document of err, e.g. NoSuchKey or
+// BucketNotEmpty, or an empty string if there is none.
+func ErrorCode(err error) string {
+	var e *s3err
+	if !errors.As(err, &e) {
+		return ""
+	}
+	return e.xmlCode()
+}
+
+// xmlCode returns the code of the error document
+func (e *s3err) xmlCode() string {
+	var v struct {
+		Code string
+	}
+	xml.Unmarshal([]byte(e.xmlBody), &v)
+	return v.Code
+}
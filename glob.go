@@ -0,0 +1,150 @@
+package s3
+
+import (
+	"path"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// Glob returns the objects whose keys, relative to Path, match the pattern.
+// Each / separated segment of the pattern uses the syntax of path.Match, and a
+// segment ** matches any number of segments.
+//
+// Only the keys below the literal part of the pattern are listed. Directory
+// levels are listed one at a time using the delimiter, so directories not
+// matching the pattern aren't descended into, until a ** requires listing all
+// keys below it.
+func (s3 *S3) Glob(pattern string) ([]Object, error) {
+	segs := strings.Split(strings.TrimLeft(pattern, "/"), "/")
+	for _, s := range segs {
+		if _, err := path.Match(s, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	var objs []Object
+	err := s3.glob("", segs, func(key string) {
+		objs = append(objs, s3.exactObject(key))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
+
+// glob matches the keys below the directory prefix dir against the remaining
+// pattern segments.
+func (s3 *S3) glob(dir string, segs []string, fn func(key string)) error {
+	// literal directories don't need to be listed
+	for len(segs) > 1 && segs[0] != "**" && !hasMeta(segs[0]) {
+		dir += segs[0] + "/"
+		segs = segs[1:]
+	}
+
+	seg := segs[0]
+	if seg == "**" {
+		it := s3.List(ListOptions{Prefix: dir})
+		for it.Next() {
+			key := it.Entry().Key
+			if matchSegments(segs, strings.Split(strings.TrimPrefix(key, dir), "/")) {
+				fn(key)
+			}
+		}
+		return it.Err()
+	}
+
+	var subdirs []string
+	it := s3.List(ListOptions{Prefix: dir + literalPrefix(seg), Delimiter: "/"})
+	for it.Next() {
+		e := it.Entry()
+		name := strings.TrimSuffix(strings.TrimPrefix(e.Key, dir), "/")
+		if ok, _ := path.Match(seg, name); !ok {
+			continue
+		}
+		if len(segs) == 1 && !e.IsPrefix {
+			fn(e.Key)
+		} else if len(segs) > 1 && e.IsPrefix {
+			subdirs = append(subdirs, e.Key)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	for _, d := range subdirs {
+		if err := s3.glob(d, segs[1:], fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MatchRegexp returns the objects whose keys, relative to Path, match re. If re
+// is anchored with ^, only the keys starting with the literal text following it
+// are listed, otherwise the whole bucket.
+func (s3 *S3) MatchRegexp(re *regexp.Regexp) ([]Object, error) {
+	var objs []Object
+	it := s3.List(ListOptions{Prefix: regexpPrefix(re)})
+	for it.Next() {
+		if key := it.Entry().Key; re.MatchString(key) {
+			objs = append(objs, s3.exactObject(key))
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
+
+// regexpPrefix returns the literal text every match of re starts with, which is
+// the case sensitive literal directly after a leading ^.
+func regexpPrefix(re *regexp.Regexp) string {
+	r, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil || r.Op != syntax.OpConcat || r.Sub[0].Op != syntax.OpBeginText {
+		return ""
+	}
+	var prefix []rune
+	for _, sub := range r.Sub[1:] {
+		if sub.Op != syntax.OpLiteral || sub.Flags&syntax.FoldCase != 0 {
+			break
+		}
+		prefix = append(prefix, sub.Rune...)
+	}
+	return string(prefix)
+}
+
+// matchSegments matches the segments of a key against pattern segments
+func matchSegments(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pat[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, `*?[\`)
+}
+
+// literalPrefix returns the part of a pattern segment before the first meta
+// character.
+func literalPrefix(seg string) string {
+	if i := strings.IndexAny(seg, `*?[\`); i >= 0 {
+		return seg[:i]
+	}
+	return seg
+}
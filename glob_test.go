@@ -0,0 +1,95 @@
+package s3
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMatchSegments(t *testing.T) {
+	for _, c := range []struct {
+		pattern, key string
+		match        bool
+	}{
+		{"logs/2026-*/app-??.json.gz", "logs/2026-01/app-01.json.gz", true},
+		{"logs/2026-*/app-??.json.gz", "logs/2026-01/app-001.json.gz", false},
+		{"logs/2026-*/app-??.json.gz", "logs/2026-01/x/app-01.json.gz", false},
+		{"logs/**/*.gz", "logs/a.gz", true},
+		{"logs/**/*.gz", "logs/a/b/c.gz", true},
+		{"logs/**/*.gz", "logs/a/b/c.txt", false},
+		{"logs/**", "logs/a/b", true},
+		{"**", "a", true},
+		{"a/**/b/**/c", "a/x/b/y/z/c", true},
+		{"a/**/b/**/c", "a/x/y/z/c", false},
+		{"[ab]/c", "b/c", true},
+		{"[ab]/c", "d/c", false},
+	} {
+		if x := matchSegments(strings.Split(c.pattern, "/"), strings.Split(c.key, "/")); x != c.match {
+			t.Fatal(c.pattern, c.key, x)
+		}
+	}
+}
+
+func TestLiteralPrefix(t *testing.T) {
+	for seg, prefix := range map[string]string{
+		"app-??.json.gz": "app-",
+		"*.txt":          "",
+		"a.txt":          "a.txt",
+		"x[0-9]":         "x",
+		`a\*`:            "a",
+	} {
+		if x := literalPrefix(seg); x != prefix {
+			t.Fatal(seg, x)
+		}
+		if x := hasMeta(seg); x != (prefix != seg) {
+			t.Fatal(seg, x)
+		}
+	}
+}
+
+func TestRegexpPrefix(t *testing.T) {
+	for re, prefix := range map[string]string{
+		`^logs/.*\.gz$`: "logs/",
+		`^ab*`:          "a",
+		`^abc`:          "abc",
+		`^a\.b+`:        "a.",
+		`logs/.*`:       "",
+		`^(?i)logs/`:    "",
+		`^a|^b`:         "",
+		`^(a|b)c`:       "",
+	} {
+		if x := regexpPrefix(regexp.MustCompile(re)); x != prefix {
+			t.Fatal(re, x)
+		}
+	}
+}
+
+func TestGlobBadPattern(t *testing.T) {
+	if _, err := (&S3{}).Glob("a/[/b"); err == nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMatchRegexpKeys(t *testing.T) {
+	var prefix string
+	c := &Client{Endpoint: "http://s3.test", HTTPClient: &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) *http.Response {
+			prefix = req.URL.Query().Get("prefix")
+			return testResponse(200, nil, `<ListBucketResult>
+  <Contents><Key>root/logs/dir/</Key></Contents>
+  <Contents><Key>root/logs/a.gz</Key></Contents>
+</ListBucketResult>`)
+		}),
+	}}
+	objs, err := c.Bucket("b", "root").MatchRegexp(regexp.MustCompile(`^logs/`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefix != "root/logs/" {
+		t.Fatal(prefix)
+	}
+	if len(objs) != 2 || objs[0].Key() != "root/logs/dir/" || objs[1].Key() != "root/logs/a.gz" {
+		t.Fatal(objs)
+	}
+}
@@ -3,9 +3,12 @@ package s3
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
+const metaPrefix = "x-amz-meta-"
+
 type Header http.Header
 
 func (h Header) Date() (time.Time, error) {
@@ -27,3 +30,90 @@ func (h Header) ContentLength() (int64, error) {
 func (h Header) ContentType() string {
 	return http.Header(h).Get("Content-Type")
 }
+
+func (h Header) CacheControl() string {
+	return http.Header(h).Get("Cache-Control")
+}
+
+func (h Header) ContentDisposition() string {
+	return http.Header(h).Get("Content-Disposition")
+}
+
+func (h Header) ContentEncoding() string {
+	return http.Header(h).Get("Content-Encoding")
+}
+
+func (h Header) ContentLanguage() string {
+	return http.Header(h).Get("Content-Language")
+}
+
+func (h Header) Expires() (time.Time, error) {
+	return time.Parse(time.RFC1123, http.Header(h).Get("Expires"))
+}
+
+// Metadata returns the user metadata with the x-amz-meta- prefix removed from
+// the lower cased keys.
+func (h Header) Metadata() map[string]string {
+	m := make(map[string]string)
+	for k, v := range h {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, metaPrefix) && len(v) > 0 {
+			m[k[len(metaPrefix):]] = v[0]
+		}
+	}
+	return m
+}
+
+// VersionID returns the version of the object, or an empty string if the bucket
+// isn't versioned.
+func (h Header) VersionID() string {
+	return http.Header(h).Get("x-amz-version-id")
+}
+
+// DeleteMarker reports whether the response refers to a delete marker. Reads
+// of a delete marker fail, so it is set on the header of the error, see
+// ErrorHeader.
+func (h Header) DeleteMarker() bool {
+	return http.Header(h).Get("x-amz-delete-marker") == "true"
+}
+
+// BucketRegion returns the region of the bucket, which S3 reports on HeadBucket
+// and on redirects.
+func (h Header) BucketRegion() string {
+	return http.Header(h).Get("x-amz-bucket-region")
+}
+
+func (h Header) StorageClass() StorageClass {
+	// S3 omits the header for STANDARD objects
+	if v := http.Header(h).Get("x-amz-storage-class"); v != "" {
+		return StorageClass(v)
+	}
+	return Standard
+}
+
+// Restore returns the restore status of an archived object, or nil if no
+// restore was requested.
+func (h Header) Restore() (*RestoreStatus, error) {
+	v := http.Header(h).Get("x-amz-restore")
+	if v == "" {
+		return nil, nil
+	}
+	return parseRestoreStatus(v)
+}
+
+// ServerSideEncryption returns the server side encryption algorithm, SSES3 or
+// SSEKMS, or an empty string if the object isn't encrypted by S3.
+func (h Header) ServerSideEncryption() string {
+	return http.Header(h).Get("x-amz-server-side-encryption")
+}
+
+// SSEKMSKeyID returns the KMS key used to encrypt the object
+func (h Header) SSEKMSKeyID() string {
+	return http.Header(h).Get("x-amz-server-side-encryption-aws-kms-key-id")
+}
+
+// SSECustomerAlgorithm returns the algorithm if the object is encrypted with a
+// customer key.
+func (h Header) SSECustomerAlgorithm() string {
+	return http.Header(h).Get("x-amz-server-side-encryption-customer-algorithm")
+}
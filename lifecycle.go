@@ -0,0 +1,227 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"time"
+)
+
+// LifecycleStatus enables or disables a lifecycle rule
+type LifecycleStatus string
+
+const (
+	LifecycleEnabled  LifecycleStatus = "Enabled"
+	LifecycleDisabled LifecycleStatus = "Disabled"
+)
+
+// LifecycleConfiguration holds the lifecycle rules of a bucket
+type LifecycleConfiguration struct {
+	XMLName xml.Name        `xml:"LifecycleConfiguration"`
+	Rules   []LifecycleRule `xml:"Rule"`
+}
+
+// LifecycleRule expires or transitions the objects matching the filter
+type LifecycleRule struct {
+	ID     string `xml:",omitempty"`
+	Filter LifecycleFilter
+	Status LifecycleStatus
+
+	Transitions                    []Transition                    `xml:"Transition"`
+	NoncurrentVersionTransitions   []NoncurrentVersionTransition   `xml:"NoncurrentVersionTransition"`
+	Expiration                     *Expiration                     `xml:",omitempty"`
+	NoncurrentVersionExpiration    *NoncurrentVersionExpiration    `xml:",omitempty"`
+	AbortIncompleteMultipartUpload *AbortIncompleteMultipartUpload `xml:",omitempty"`
+}
+
+// LifecycleFilter selects the objects a rule applies to. All conditions must
+// hold, the zero filter matches all objects.
+type LifecycleFilter struct {
+	Prefix                string
+	Tags                  []Tag
+	ObjectSizeGreaterThan int64
+	ObjectSizeLessThan    int64
+}
+
+// Tag is an object tag
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Transition moves current versions to another storage class, Days after
+// creation or on Date if it is set. Days may be 0 to transition objects right
+// away.
+type Transition struct {
+	Days         int
+	Date         *time.Time `xml:",omitempty"`
+	StorageClass StorageClass
+}
+
+// Expiration deletes current versions, which leaves a delete marker in
+// versioned buckets. Either Days after creation or Date must be set, or
+// ExpiredObjectDeleteMarker to remove delete markers without other versions.
+type Expiration struct {
+	Days                      int        `xml:",omitempty"`
+	Date                      *time.Time `xml:",omitempty"`
+	ExpiredObjectDeleteMarker bool       `xml:",omitempty"`
+}
+
+// NoncurrentVersionTransition moves versions to another storage class
+// NoncurrentDays after they became noncurrent. NewerNoncurrentVersions keeps
+// that many newer noncurrent versions in place.
+type NoncurrentVersionTransition struct {
+	NoncurrentDays          int
+	NewerNoncurrentVersions int `xml:",omitempty"`
+	StorageClass            StorageClass
+}
+
+// NoncurrentVersionExpiration permanently deletes versions NoncurrentDays after
+// they became noncurrent. NewerNoncurrentVersions keeps that many newer
+// noncurrent versions.
+type NoncurrentVersionExpiration struct {
+	NoncurrentDays          int
+	NewerNoncurrentVersions int `xml:",omitempty"`
+}
+
+// AbortIncompleteMultipartUpload aborts multipart uploads that weren't
+// completed within DaysAfterInitiation.
+type AbortIncompleteMultipartUpload struct {
+	DaysAfterInitiation int
+}
+
+// lifecycleFilter is the XML form of a filter. Multiple conditions have to be
+// wrapped in And.
+type lifecycleFilter struct {
+	Prefix                string           `xml:",omitempty"`
+	Tag                   []Tag            `xml:",omitempty"`
+	ObjectSizeGreaterThan int64            `xml:",omitempty"`
+	ObjectSizeLessThan    int64            `xml:",omitempty"`
+	And                   *lifecycleFilter `xml:",omitempty"`
+}
+
+func (f LifecycleFilter) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	v := lifecycleFilter{
+		Prefix:                f.Prefix,
+		Tag:                   f.Tags,
+		ObjectSizeGreaterThan: f.ObjectSizeGreaterThan,
+		ObjectSizeLessThan:    f.ObjectSizeLessThan,
+	}
+
+	n := len(f.Tags)
+	for _, ok := range []bool{f.Prefix != "", f.ObjectSizeGreaterThan > 0, f.ObjectSizeLessThan > 0} {
+		if ok {
+			n++
+		}
+	}
+	if n > 1 {
+		and := v
+		v = lifecycleFilter{And: &and}
+	}
+	return e.EncodeElement(v, start)
+}
+
+func (f *LifecycleFilter) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v lifecycleFilter
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	if v.And != nil {
+		v = *v.And
+	}
+	*f = LifecycleFilter{
+		Prefix:                v.Prefix,
+		Tags:                  v.Tag,
+		ObjectSizeGreaterThan: v.ObjectSizeGreaterThan,
+		ObjectSizeLessThan:    v.ObjectSizeLessThan,
+	}
+	return nil
+}
+
+// MarshalXML sends Days whenever Date is unset, so 0 days are not left out.
+func (t Transition) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	v := struct {
+		Days         *int       `xml:",omitempty"`
+		Date         *time.Time `xml:",omitempty"`
+		StorageClass StorageClass
+	}{Date: t.Date, StorageClass: t.StorageClass}
+	if t.Date == nil || t.Date.IsZero() {
+		v.Days, v.Date = &t.Days, nil
+	}
+	return e.EncodeElement(v, start)
+}
+
+func (r *LifecycleRule) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type rule LifecycleRule
+	var v struct {
+		rule
+
+		// rules created with the old API have a prefix instead of a filter
+		Prefix string
+	}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	*r = LifecycleRule(v.rule)
+	if v.Prefix != "" && r.Filter.Prefix == "" {
+		r.Filter.Prefix = v.Prefix
+	}
+	return nil
+}
+
+// GetLifecycle returns the lifecycle configuration of the bucket. A bucket
+// without one returns an empty configuration.
+func (s3 *S3) GetLifecycle() (*LifecycleConfiguration, error) {
+	req, err := http.NewRequest("GET", s3.bucketURL("?lifecycle"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s3.do(req, 200, "error getting lifecycle")
+	if ErrorCode(err) == "NoSuchLifecycleConfiguration" {
+		return new(LifecycleConfiguration), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	lc := new(LifecycleConfiguration)
+	if err := xml.NewDecoder(resp.Body).Decode(lc); err != nil {
+		return nil, err
+	}
+	return lc, nil
+}
+
+// PutLifecycle replaces the lifecycle configuration of the bucket
+func (s3 *S3) PutLifecycle(lc *LifecycleConfiguration) error {
+	b, err := xml.Marshal(lc)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("PUT", s3.bucketURL("?lifecycle"), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-MD5", contentMD5(b))
+
+	resp, err := s3.do(req, 200, "error putting lifecycle")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DeleteLifecycle removes all lifecycle rules of the bucket
+func (s3 *S3) DeleteLifecycle() error {
+	req, err := http.NewRequest("DELETE", s3.bucketURL("?lifecycle"), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s3.do(req, 204, "error deleting lifecycle")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,142 @@
+package s3
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const lifecycleXML = `<?xml version="1.0" encoding="UTF-8"?>
+<LifecycleConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Rule>
+    <ID>logs</ID>
+    <Filter>
+      <And>
+        <Prefix>logs/</Prefix>
+        <Tag><Key>keep</Key><Value>false</Value></Tag>
+        <ObjectSizeGreaterThan>1024</ObjectSizeGreaterThan>
+      </And>
+    </Filter>
+    <Status>Enabled</Status>
+    <Transition>
+      <Days>30</Days>
+      <StorageClass>STANDARD_IA</StorageClass>
+    </Transition>
+    <Transition>
+      <Days>90</Days>
+      <StorageClass>GLACIER</StorageClass>
+    </Transition>
+    <Expiration>
+      <Days>365</Days>
+    </Expiration>
+  </Rule>
+  <Rule>
+    <ID>legacy</ID>
+    <Prefix>tmp/</Prefix>
+    <Status>Disabled</Status>
+    <Expiration>
+      <Date>2027-01-01T00:00:00.000Z</Date>
+    </Expiration>
+    <NoncurrentVersionExpiration>
+      <NoncurrentDays>7</NoncurrentDays>
+      <NewerNoncurrentVersions>2</NewerNoncurrentVersions>
+    </NoncurrentVersionExpiration>
+    <AbortIncompleteMultipartUpload>
+      <DaysAfterInitiation>3</DaysAfterInitiation>
+    </AbortIncompleteMultipartUpload>
+  </Rule>
+</LifecycleConfiguration>`
+
+func TestLifecycleConfiguration(t *testing.T) {
+	var lc LifecycleConfiguration
+	if err := xml.Unmarshal([]byte(lifecycleXML), &lc); err != nil {
+		t.Fatal(err)
+	}
+	if x := len(lc.Rules); x != 2 {
+		t.Fatal(x)
+	}
+
+	r := lc.Rules[0]
+	if r.ID != "logs" || r.Status != LifecycleEnabled {
+		t.Fatal(r)
+	}
+	f := LifecycleFilter{Prefix: "logs/", Tags: []Tag{{"keep", "false"}}, ObjectSizeGreaterThan: 1024}
+	if !reflect.DeepEqual(r.Filter, f) {
+		t.Fatal(r.Filter)
+	}
+	if x := r.Transitions; len(x) != 2 || x[1].Days != 90 || x[1].StorageClass != Glacier {
+		t.Fatal(x)
+	}
+	if x := r.Expiration; x == nil || x.Days != 365 {
+		t.Fatal(x)
+	}
+
+	r = lc.Rules[1]
+	if r.Filter.Prefix != "tmp/" || r.Status != LifecycleDisabled {
+		t.Fatal(r)
+	}
+	if x := r.Expiration; x == nil || !x.Date.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal(x)
+	}
+	if x := r.NoncurrentVersionExpiration; x == nil || x.NoncurrentDays != 7 || x.NewerNoncurrentVersions != 2 {
+		t.Fatal(x)
+	}
+	if x := r.AbortIncompleteMultipartUpload; x == nil || x.DaysAfterInitiation != 3 {
+		t.Fatal(x)
+	}
+
+	// marshal and parse again
+	b, err := xml.Marshal(&lc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lc2 LifecycleConfiguration
+	if err := xml.Unmarshal(b, &lc2); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lc.Rules, lc2.Rules) {
+		t.Fatal(string(b))
+	}
+}
+
+func TestLifecycleFilter(t *testing.T) {
+	for _, c := range []struct {
+		f   LifecycleFilter
+		xml string
+	}{
+		{LifecycleFilter{}, `<Filter></Filter>`},
+		{LifecycleFilter{Prefix: "a/"}, `<Filter><Prefix>a/</Prefix></Filter>`},
+		{LifecycleFilter{ObjectSizeLessThan: 10}, `<Filter><ObjectSizeLessThan>10</ObjectSizeLessThan></Filter>`},
+		{LifecycleFilter{Tags: []Tag{{"k", "v"}, {"k2", "v2"}}}, `<Filter><And><Tag><Key>k</Key><Value>v</Value></Tag><Tag><Key>k2</Key><Value>v2</Value></Tag></And></Filter>`},
+	} {
+		b, err := xml.Marshal(c.f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if x := strings.Replace(string(b), "LifecycleFilter", "Filter", -1); x != c.xml {
+			t.Fatal(x)
+		}
+	}
+}
+
+func TestTransitionMarshal(t *testing.T) {
+	date := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		t   Transition
+		xml string
+	}{
+		{Transition{StorageClass: Glacier}, `<Transition><Days>0</Days><StorageClass>GLACIER</StorageClass></Transition>`},
+		{Transition{Days: 30, StorageClass: StandardIA}, `<Transition><Days>30</Days><StorageClass>STANDARD_IA</StorageClass></Transition>`},
+		{Transition{Date: &date, StorageClass: Glacier}, `<Transition><Date>2027-01-01T00:00:00Z</Date><StorageClass>GLACIER</StorageClass></Transition>`},
+	} {
+		b, err := xml.Marshal(c.t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if x := string(b); x != c.xml {
+			t.Fatal(x)
+		}
+	}
+}
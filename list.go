@@ -0,0 +1,254 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListOptions configure a bucket listing. Prefix and StartAfter are relative to
+// Path.
+type ListOptions struct {
+	Prefix string
+
+	// Delimiter groups keys containing it after the prefix into common
+	// prefixes, which are listed instead of the keys.
+	Delimiter string
+
+	// StartAfter starts the listing after this key
+	StartAfter string
+
+	// MaxKeys is the number of keys fetched per page. S3 returns at most 1000.
+	MaxKeys int
+
+	// ContinuationToken resumes a listing from ListIterator.ContinuationToken
+	ContinuationToken string
+
+	// FetchOwner includes the owner of each object
+	FetchOwner bool
+}
+
+// ListEntry is an object or, if IsPrefix is set, a common prefix. Keys are
+// relative to Path.
+type ListEntry struct {
+	Key          string
+	IsPrefix     bool
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	StorageClass StorageClass
+	Owner        *Owner
+}
+
+// ListIterator lazily fetches the pages of a listing. Entries are returned in
+// key order.
+//
+//	it := s3.List(s3.ListOptions{Prefix: "logs/"})
+//	for it.Next() {
+//		e := it.Entry()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type ListIterator struct {
+	s3     *S3
+	params url.Values
+	page   []ListEntry
+	entry  ListEntry
+	token  string
+	done   bool
+	err    error
+}
+
+// List returns an iterator over the objects in the bucket
+func (s3 *S3) List(opts ListOptions) *ListIterator {
+	params := make(url.Values)
+	if p := s3.prefix(opts.Prefix); p != "" {
+		params.Set("prefix", p)
+	}
+	if opts.Delimiter != "" {
+		params.Set("delimiter", opts.Delimiter)
+	}
+	if opts.StartAfter != "" {
+		params.Set("start-after", s3.prefix(opts.StartAfter))
+	}
+	if opts.MaxKeys > 0 {
+		params.Set("max-keys", strconv.Itoa(opts.MaxKeys))
+	}
+	if opts.FetchOwner {
+		params.Set("fetch-owner", "true")
+	}
+	return &ListIterator{s3: s3, params: params, token: opts.ContinuationToken}
+}
+
+// Next advances to the next entry and reports whether there is one. It returns
+// false at the end of the listing or on error.
+func (it *ListIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.fetch()
+	}
+	it.entry, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Entry returns the current entry
+func (it *ListIterator) Entry() ListEntry {
+	return it.entry
+}
+
+// Err returns the error that ended the iteration, if any
+func (it *ListIterator) Err() error {
+	return it.err
+}
+
+// ContinuationToken returns the token to resume the listing after the current
+// page, or an empty string on the last page.
+func (it *ListIterator) ContinuationToken() string {
+	return it.token
+}
+
+func (it *ListIterator) fetch() {
+	if it.token != "" {
+		it.params.Set("continuation-token", it.token)
+	}
+	res, err := it.s3.listObjects(it.params)
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	it.token = res.NextContinuationToken
+	it.done = !res.IsTruncated
+	it.page = res.entries(it.s3)
+}
+
+// listResult is a page of a ListObjectsV2 response
+type listResult struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key          string
+		Size         int64
+		ETag         string
+		LastModified time.Time
+		StorageClass StorageClass
+		Owner        *Owner
+	}
+	CommonPrefixes []struct {
+		Prefix string
+	}
+}
+
+// entries merges the objects and common prefixes in key order
+func (res *listResult) entries(s3 *S3) []ListEntry {
+	e := make([]ListEntry, 0, len(res.Contents)+len(res.CommonPrefixes))
+	i, j := 0, 0
+	for i < len(res.Contents) || j < len(res.CommonPrefixes) {
+		if j == len(res.CommonPrefixes) || (i < len(res.Contents) && res.Contents[i].Key < res.CommonPrefixes[j].Prefix) {
+			c := res.Contents[i]
+			e = append(e, ListEntry{
+				Key:          s3.relKey(c.Key),
+				Size:         c.Size,
+				ETag:         trimETag(c.ETag),
+				LastModified: c.LastModified,
+				StorageClass: c.StorageClass,
+				Owner:        c.Owner,
+			})
+			i++
+		} else {
+			e = append(e, ListEntry{
+				Key:      s3.relKey(res.CommonPrefixes[j].Prefix),
+				IsPrefix: true,
+			})
+			j++
+		}
+	}
+	return e
+}
+
+// listObjects requests a single page of a bucket listing
+func (s3 *S3) listObjects(params url.Values) (*listResult, error) {
+	req, err := http.NewRequest("GET", s3.bucketURL(""), nil)
+	if err != nil {
+		return nil, err
+	}
+	p := url.Values{"list-type": {"2"}}
+	for k, v := range params {
+		p[k] = v
+	}
+
+	resp, err := s3.doParams(req, p, 200, "error listing objects")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	res := new(listResult)
+	if err := xml.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// listKeys calls fn for every key with the prefix, which is relative to the
+// bucket root.
+func (s3 *S3) listKeys(prefix string, fn func(key string, size int64) error) error {
+	root := *s3
+	root.Path = ""
+	it := root.List(ListOptions{Prefix: prefix})
+	for it.Next() {
+		e := it.Entry()
+		if err := fn(e.Key, e.Size); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// ReadDir lists a single level of the key hierarchy below prefix, using / as
+// the delimiter. Directories are the common prefixes and keep their trailing
+// slash. All names are relative to Path.
+func (s3 *S3) ReadDir(prefix string) (dirs []string, files []ListEntry, err error) {
+	entries, err := s3.readDir(dirPrefix(prefix))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, e := range entries {
+		if e.IsPrefix {
+			dirs = append(dirs, e.Key)
+		} else {
+			files = append(files, e)
+		}
+	}
+	return dirs, files, nil
+}
+
+// readDir returns the entries of the directory prefix in key order
+func (s3 *S3) readDir(prefix string) ([]ListEntry, error) {
+	var entries []ListEntry
+	it := s3.List(ListOptions{Prefix: prefix, Delimiter: "/"})
+	for it.Next() {
+		// skip the marker object some tools create for empty directories
+		if e := it.Entry(); e.IsPrefix || e.Key != prefix {
+			entries = append(entries, e)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// dirPrefix turns a directory name into a listing prefix ending in a slash
+func dirPrefix(dir string) string {
+	dir = strings.Trim(dir, `/`)
+	if dir == "" {
+		return ""
+	}
+	return dir + `/`
+}
@@ -0,0 +1,229 @@
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const listXML = `<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Name>bucket</Name>
+  <Prefix>root/</Prefix>
+  <KeyCount>3</KeyCount>
+  <MaxKeys>3</MaxKeys>
+  <Delimiter>/</Delimiter>
+  <IsTruncated>true</IsTruncated>
+  <NextContinuationToken>1ueGcxLPRx1Tr/XYExHnhbYLgveDs2J/wm36Hy4vbOwM=</NextContinuationToken>
+  <Contents>
+    <Key>root/a.txt</Key>
+    <LastModified>2009-10-12T17:50:30.000Z</LastModified>
+    <ETag>"fba9dede5f27731c9771645a39863328"</ETag>
+    <Size>434234</Size>
+    <StorageClass>STANDARD</StorageClass>
+    <Owner>
+      <ID>owner-id</ID>
+    </Owner>
+  </Contents>
+  <Contents>
+    <Key>root/c.txt</Key>
+    <LastModified>2009-10-12T17:50:30.000Z</LastModified>
+    <ETag>"fba9dede5f27731c9771645a39863328"</ETag>
+    <Size>1</Size>
+    <StorageClass>GLACIER</StorageClass>
+  </Contents>
+  <CommonPrefixes>
+    <Prefix>root/b/</Prefix>
+  </CommonPrefixes>
+</ListBucketResult>`
+
+func TestListResult(t *testing.T) {
+	var res listResult
+	if err := xml.Unmarshal([]byte(listXML), &res); err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsTruncated || res.NextContinuationToken == "" {
+		t.Fatal(res)
+	}
+
+	e := res.entries(&S3{Path: "root"})
+	if x := len(e); x != 3 {
+		t.Fatal(x)
+	}
+	if x := e[0]; x.Key != "a.txt" || x.IsPrefix || x.Size != 434234 || x.ETag != "fba9dede5f27731c9771645a39863328" || x.Owner.ID != "owner-id" {
+		t.Fatal(x)
+	}
+	if x := e[0].LastModified; !x.Equal(time.Date(2009, 10, 12, 17, 50, 30, 0, time.UTC)) {
+		t.Fatal(x)
+	}
+	if x := e[1]; x.Key != "b/" || !x.IsPrefix {
+		t.Fatal(x)
+	}
+	if x := e[2]; x.Key != "c.txt" || x.StorageClass != Glacier || x.Owner != nil {
+		t.Fatal(x)
+	}
+}
+
+func TestListParams(t *testing.T) {
+	it := (&S3{Path: "root"}).List(ListOptions{
+		Delimiter:  "/",
+		StartAfter: "a.txt",
+		MaxKeys:    10,
+	})
+	for k, v := range map[string]string{
+		"prefix":      "root/",
+		"delimiter":   "/",
+		"start-after": "root/a.txt",
+		"max-keys":    "10",
+		"fetch-owner": "",
+	} {
+		if x := it.params.Get(k); x != v {
+			t.Fatal(k, x)
+		}
+	}
+}
+
+func TestDirPrefix(t *testing.T) {
+	for in, out := range map[string]string{
+		"":       "",
+		"/":      "",
+		"a":      "a/",
+		"/a/b/":  "a/b/",
+		"a/b":    "a/b/",
+		"a//b//": "a//b/",
+	} {
+		if x := dirPrefix(in); x != out {
+			t.Fatal(in, x)
+		}
+	}
+}
+
+const versionsXML = `<?xml version="1.0" encoding="UTF-8"?>
+<ListVersionsResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Name>bucket</Name>
+  <Prefix>root/</Prefix>
+  <KeyMarker></KeyMarker>
+  <VersionIdMarker></VersionIdMarker>
+  <NextKeyMarker>root/b.txt</NextKeyMarker>
+  <NextVersionIdMarker>v3</NextVersionIdMarker>
+  <MaxKeys>3</MaxKeys>
+  <IsTruncated>true</IsTruncated>
+  <DeleteMarker>
+    <Key>root/a.txt</Key>
+    <VersionId>v1</VersionId>
+    <IsLatest>true</IsLatest>
+    <LastModified>2009-10-15T17:50:30.000Z</LastModified>
+  </DeleteMarker>
+  <Version>
+    <Key>root/a.txt</Key>
+    <VersionId>v2</VersionId>
+    <IsLatest>false</IsLatest>
+    <LastModified>2009-10-12T17:50:30.000Z</LastModified>
+    <ETag>"fba9dede5f27731c9771645a39863328"</ETag>
+    <Size>434234</Size>
+    <StorageClass>STANDARD</StorageClass>
+  </Version>
+  <Version>
+    <Key>root/b.txt</Key>
+    <VersionId>v3</VersionId>
+    <IsLatest>true</IsLatest>
+    <LastModified>2009-10-12T17:50:30.000Z</LastModified>
+    <ETag>"fba9dede5f27731c9771645a39863328"</ETag>
+    <Size>1</Size>
+    <StorageClass>STANDARD</StorageClass>
+  </Version>
+</ListVersionsResult>`
+
+func TestVersionsResult(t *testing.T) {
+	var res versionsResult
+	if err := xml.Unmarshal([]byte(versionsXML), &res); err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsTruncated || res.NextKeyMarker != "root/b.txt" || res.NextVersionIdMarker != "v3" {
+		t.Fatal(res)
+	}
+
+	v := res.versions(&S3{Path: "root"})
+	if x := len(v); x != 3 {
+		t.Fatal(x)
+	}
+	if x := v[0]; x.Key != "a.txt" || x.VersionID != "v1" || !x.IsDeleteMarker || !x.IsLatest {
+		t.Fatal(x)
+	}
+	if x := v[1]; x.Key != "a.txt" || x.VersionID != "v2" || x.IsDeleteMarker || x.IsLatest || x.Size != 434234 || x.ETag != "fba9dede5f27731c9771645a39863328" {
+		t.Fatal(x)
+	}
+	if x := v[2]; x.Key != "b.txt" || x.VersionID != "v3" {
+		t.Fatal(x)
+	}
+}
+
+func TestVersionQuery(t *testing.T) {
+	if x := versionQuery(""); x != "" {
+		t.Fatal(x)
+	}
+	if x := versionQuery("3/L4kqtJl+40"); x != "?versionId=3%2FL4kqtJl%2B40" {
+		t.Fatal(x)
+	}
+}
+
+func TestWalkListings(t *testing.T) {
+	var (
+		started, finished int32
+		release           = make(chan struct{})
+	)
+	c := &Client{Endpoint: "http://s3.test", HTTPClient: &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) *http.Response {
+			atomic.AddInt32(&started, 1)
+			defer atomic.AddInt32(&finished, 1)
+
+			var b strings.Builder
+			b.WriteString("<ListBucketResult>")
+			if p := req.URL.Query().Get("prefix"); p == "" {
+				for i := 0; i < 50; i++ {
+					fmt.Fprintf(&b, "<CommonPrefixes><Prefix>d%02d/</Prefix></CommonPrefixes>", i)
+				}
+			} else {
+				// sub directories stay in flight until released
+				<-release
+				fmt.Fprintf(&b, "<Contents><Key>%sf</Key><Size>1</Size></Contents>", p)
+			}
+			b.WriteString("</ListBucketResult>")
+			return testResponse(200, nil, b.String())
+		}),
+	}}
+	s3 := c.Bucket("b")
+
+	err := s3.Walk("", func(key string, e ListEntry, err error) error {
+		if key == "d00/" {
+			close(release)
+			return fs.SkipAll
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Walk waited for the listings in flight and dropped all others
+	n := atomic.LoadInt32(&started)
+	if n > 1+walkLookahead || atomic.LoadInt32(&finished) != n {
+		t.Fatal(n, atomic.LoadInt32(&finished))
+	}
+
+	var keys []string
+	err = s3.Walk("", func(key string, e ListEntry, err error) error {
+		keys = append(keys, key)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 101 || keys[1] != "d00/" || keys[2] != "d00/f" || keys[100] != "d49/f" {
+		t.Fatal(len(keys), keys)
+	}
+}
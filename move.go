@@ -0,0 +1,163 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MoveOptions configure moves. The embedded CopyOptions are used for copying
+// each object.
+type MoveOptions struct {
+	CopyOptions
+
+	// Concurrency is the number of objects moved at once by MovePrefix.
+	// Defaults to 5.
+	Concurrency int
+
+	// Progress is called after each object of a MovePrefix was moved or failed
+	// to move. Keys are relative to Path. It may be called concurrently.
+	Progress func(src, dst string, size int64, err error)
+}
+
+// MoveReport summarizes a MovePrefix
+type MoveReport struct {
+	Moved int
+	Bytes int64
+
+	// Failed holds the errors of objects that couldn't be moved by their source
+	// key. Failed objects are left in place at the source.
+	Failed map[string]error
+}
+
+var errMoveIntoSelf = errors.New("s3: can't move a prefix into itself")
+
+func (o *object) MoveTo(dst Object, opts ...CopyOptions) error {
+	_, err := o.moveTo(plainObject(dst), copyOptions(opts))
+	return err
+}
+
+// moveTo copies o to dst, checks the copy and deletes o. It returns the number of
+// bytes moved.
+func (o *object) moveTo(dst *object, co CopyOptions) (int64, error) {
+	if o.s3.Bucket == dst.s3.Bucket && o.Key() == dst.Key() {
+		return 0, nil
+	}
+
+	h, err := o.Head(ReadOptions{Encryption: co.SourceEncryption, VersionID: co.SourceVersionID})
+	if err != nil {
+		return 0, err
+	}
+	size, err := h.ContentLength()
+	if err != nil {
+		return 0, err
+	}
+
+	// make sure the object doesn't change in between
+	if co.SourceIfMatch == "" {
+		co.SourceIfMatch = h.ETag()
+	}
+
+	c := new(Copier)
+	res, err := c.Copy(o, dst, co)
+	if err != nil {
+		return 0, err
+	}
+
+	dh, err := dst.Head(ReadOptions{Encryption: co.Encryption})
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := dh.ContentLength(); n != size {
+		return 0, fmt.Errorf("s3: moved object has %d instead of %d bytes", n, size)
+	}
+	// the content MD5 is only comparable for plain single part copies
+	etag := res.ETag
+	if size <= MaxCopySize && md5ETag(h) && md5ETag(dh) {
+		etag = trimETag(h.ETag())
+	}
+	if x := trimETag(dh.ETag()); x != etag {
+		return 0, fmt.Errorf("s3: moved object has etag %s instead of %s", x, etag)
+	}
+
+	return size, o.Delete(DeleteOptions{VersionID: co.SourceVersionID})
+}
+
+// md5ETag reports whether the ETag of an object is the MD5 of its content, which
+// isn't the case for multipart uploads and KMS or customer key encryption.
+func md5ETag(h Header) bool {
+	return !strings.Contains(h.ETag(), "-") &&
+		!strings.HasPrefix(h.ServerSideEncryption(), SSEKMS) &&
+		h.SSECustomerAlgorithm() == ""
+}
+
+// MovePrefix moves all objects with the prefix src to the prefix dst by
+// replacing one prefix with the other. Both are relative to Path. The returned
+// error is set if listing failed or any object couldn't be moved, in which case
+// the report holds the failures.
+func (s3 *S3) MovePrefix(src, dst string, opts ...MoveOptions) (*MoveReport, error) {
+	var mo MoveOptions
+	if len(opts) > 0 {
+		mo = opts[0]
+	}
+	concurrency := mo.Concurrency
+	if concurrency <= 0 {
+		concurrency = nConcurrentUploads
+	}
+
+	src, dst = s3.prefix(src), s3.prefix(dst)
+	if strings.HasPrefix(dst, src) {
+		return nil, errMoveIntoSelf
+	}
+
+	var (
+		wg     sync.WaitGroup
+		m      sync.Mutex
+		report = &MoveReport{Failed: make(map[string]error)}
+		keys   = make(chan [2]string)
+		sizes  = make(map[string]int64)
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range keys {
+				n, err := s3.raw(k[0]).moveTo(s3.raw(k[1]), mo.CopyOptions)
+
+				m.Lock()
+				if err != nil {
+					report.Failed[s3.relKey(k[0])] = err
+				} else {
+					report.Moved++
+					report.Bytes += n
+				}
+				size := sizes[k[0]]
+				delete(sizes, k[0])
+				m.Unlock()
+
+				if mo.Progress != nil {
+					mo.Progress(s3.relKey(k[0]), s3.relKey(k[1]), size, err)
+				}
+			}
+		}()
+	}
+
+	err := s3.listKeys(src, func(key string, size int64) error {
+		m.Lock()
+		sizes[key] = size
+		m.Unlock()
+		keys <- [2]string{key, dst + strings.TrimPrefix(key, src)}
+		return nil
+	})
+	close(keys)
+	wg.Wait()
+
+	if err != nil {
+		return report, err
+	}
+	if n := len(report.Failed); n > 0 {
+		return report, fmt.Errorf("s3: failed to move %d of %d objects", n, n+report.Moved)
+	}
+	return report, nil
+}
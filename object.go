@@ -1,14 +1,15 @@
 package s3
 
 import (
+	"bytes"
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -39,67 +40,188 @@ type Object interface {
 	S3() S3
 
 	// Writer returns a new upload io.Writer
-	Writer() Writer
+	Writer(opts ...WriteOptions) Writer
+
+	// Put uploads the contents of r with a single PUT request and returns the
+	// response header. Use Writer for large objects.
+	Put(r io.Reader, opts ...WriteOptions) (Header, error)
 
 	// Reader returns a new ReadCloser to read the file
-	Reader() (io.ReadCloser, http.Header, error)
+	Reader(opts ...ReadOptions) (io.ReadCloser, http.Header, error)
 
-	// Exists checks if an object with the specified key already exists
-	Exists() (bool, error)
+	// Exists checks if an object with the specified key already exists. Objects
+	// encrypted with a customer key need the key in the options. Responses other
+	// than found or not found are returned as error.
+	Exists(opts ...ReadOptions) (bool, error)
 
-	// Delete deletes an object
-	Delete() error
+	// Delete deletes an object. In a versioned bucket, this creates a delete
+	// marker unless a version is specified.
+	Delete(opts ...DeleteOptions) error
 
 	// Head does a HEAD request and returns the header
-	Head() (Header, error)
+	Head(opts ...ReadOptions) (Header, error)
 
-	// ExpiringURL returns a signed, expiring URL for the object
+	// ExpiringURL returns a signed, expiring URL for the object. It is signed
+	// with Signature Version 4 once the bucket region is known.
 	ExpiringURL(expiresIn time.Duration) (*url.URL, error)
 
 	// FormURL returns a signed URL for multipart form uploads
 	FormURL(acl ACL, policy Policy, query ...url.Values) (*url.URL, error)
+
+	// GetACL returns the access control list of the object
+	GetACL() (*AccessControlPolicy, error)
+
+	// PutACL replaces the access control list of the object
+	PutACL(acp *AccessControlPolicy) error
+
+	// Restore requests a temporary copy of an archived object that is kept for
+	// the given number of days. Restoring is asynchronous, use Head and
+	// Header.Restore to poll the status.
+	Restore(days int, tier Tier) error
+
+	// CopyTo copies the object to dst on the server side. dst may belong to a
+	// different bucket. Use a Copier for objects larger than MaxCopySize.
+	CopyTo(dst Object, opts ...CopyOptions) (*CopyResult, error)
+
+	// MoveTo copies the object to dst on the server side, verifies the size and
+	// ETag of the copy and deletes the object.
+	MoveTo(dst Object, opts ...CopyOptions) error
+
+	// Versions returns the versions and delete markers of the object, newest
+	// first.
+	Versions() ([]ObjectVersion, error)
+
+	// RestoreVersion makes a previous version the current version of the
+	// object. Delete markers above the newest version are removed, older
+	// versions are copied on top of the current one.
+	RestoreVersion(versionID string) error
 }
 
 type object struct {
 	key string
 	s3  S3
+
+	// exact keeps the key as is, for keys taken from a listing
+	exact bool
 }
 
 func (o *object) Key() string {
+	key := o.key
+	if !o.exact {
+		key = trim(key)
+	}
 	if p := trim(o.s3.Path); p != "" {
-		return p + `/` + trim(o.key)
+		return p + `/` + key
 	}
-	return trim(o.key)
+	return key
 }
 
 func (o *object) S3() S3 {
 	return o.s3
 }
 
-func (o *object) Writer() Writer {
-	return newWriter(o)
+func (o *object) Writer(opts ...WriteOptions) Writer {
+	wo := writeOptions(opts)
+	w := newWriter(o, wo)
+	if wo.Compression != "" {
+		return newCompressWriter(w, wo.Compression)
+	}
+	return w
 }
 
-func (o *object) Reader() (io.ReadCloser, http.Header, error) {
-	resp, err := o.request("GET", 200, "error creating reader")
+func (o *object) Put(r io.Reader, opts ...WriteOptions) (Header, error) {
+	// S3 needs the content length up front
+	wo := writeOptions(opts)
+	var buf bytes.Buffer
+	if wo.Compression != "" {
+		zw, err := compress(&buf, wo.Compression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(zw, r); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	} else if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("PUT", o.url(""), &buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", o.contentType())
+	wo.setHeaders(req.Header)
+	wo.setConditions(req.Header)
+
+	resp, err := o.s3.do(req, 200, "error putting object")
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return Header(resp.Header), nil
+}
+
+func (o *object) Reader(opts ...ReadOptions) (io.ReadCloser, http.Header, error) {
+	ro := readOptions(opts)
+	h := ro.header()
+	ro.setRange(h)
+
+	code := 200
+	if ro.ranged() {
+		code = 206
+	}
+	resp, err := o.request("GET", versionQuery(ro.VersionID), h, code, "error creating reader")
 	if err != nil {
 		return nil, nil, err
 	}
+	if ro.Decompress {
+		if c := compression(Header(resp.Header)); c != "" {
+			if ro.ranged() {
+				resp.Body.Close()
+				return nil, nil, errCompressedRange
+			}
+			r, err := decompressStream(resp.Body, c)
+			if err != nil {
+				resp.Body.Close()
+				return nil, nil, err
+			}
+			return r, resp.Header, nil
+		}
+	}
 	return resp.Body, resp.Header, nil
 }
 
-func (o *object) Exists() (bool, error) {
-	resp, err := o.request("HEAD", 0, "")
+func (o *object) Exists(opts ...ReadOptions) (bool, error) {
+	ro := readOptions(opts)
+	resp, err := o.request("HEAD", versionQuery(ro.VersionID), ro.header(), 0, "")
 	if err != nil {
 		return false, err
 	}
-	resp.Body.Close()
-	return (resp.StatusCode == 200), err
-
+	defer resp.Body.Close()
+
+	switch c := resp.StatusCode; c {
+	case 200:
+		return true, nil
+	case 404:
+		return false, nil
+	default:
+		return false, newS3Error(resp, "s3: error checking existence (%s)", http.StatusText(c))
+	}
 }
 
-func (o *object) Delete() error {
-	resp, err := o.request("DELETE", 204, "error deleting object")
+func (o *object) Delete(opts ...DeleteOptions) error {
+	var do DeleteOptions
+	if len(opts) > 0 {
+		do = opts[0]
+	}
+	h := make(http.Header)
+	if do.MFA != "" {
+		h.Set("x-amz-mfa", do.MFA)
+	}
+	resp, err := o.request("DELETE", versionQuery(do.VersionID), h, 204, "error deleting object")
 	if err != nil {
 		return err
 	}
@@ -107,8 +229,9 @@ func (o *object) Delete() error {
 	return err
 }
 
-func (o *object) Head() (Header, error) {
-	resp, err := o.request("HEAD", 200, "error getting head")
+func (o *object) Head(opts ...ReadOptions) (Header, error) {
+	ro := readOptions(opts)
+	resp, err := o.request("HEAD", versionQuery(ro.VersionID), ro.header(), 200, "error getting head")
 	if err != nil {
 		return nil, err
 	}
@@ -117,6 +240,15 @@ func (o *object) Head() (Header, error) {
 }
 
 func (o *object) ExpiringURL(expiresIn time.Duration) (*url.URL, error) {
+	if region := o.s3.signingRegion(); region != "" {
+		u, err := url.Parse(o.url(""))
+		if err != nil {
+			return nil, err
+		}
+		o.s3.presignV4(u, region, time.Now(), expiresIn)
+		return u, nil
+	}
+
 	// create signature string
 	// TODO(erik): unify this with the request signing method.
 	method := "GET"
@@ -125,14 +257,15 @@ func (o *object) ExpiringURL(expiresIn time.Duration) (*url.URL, error) {
 	toSign := method + "\n\n\n" + expires + "\n" + cres
 
 	// generate signature
-	mac := hmac.New(sha1.New, []byte(o.s3.Secret))
+	key, secret := o.s3.credentials()
+	mac := hmac.New(sha1.New, []byte(secret))
 	mac.Write([]byte(toSign))
 
 	sig := strings.TrimSpace(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
 
 	// assemble url
 	var v = make(url.Values)
-	v.Set("AWSAccessKeyId", o.s3.AccessKey)
+	v.Set("AWSAccessKeyId", key)
 	v.Set("Expires", expires)
 	v.Set("Signature", sig)
 
@@ -152,11 +285,12 @@ func (o *object) FormURL(acl ACL, policy Policy, query ...url.Values) (*url.URL,
 	}
 
 	policy64 := base64.StdEncoding.EncodeToString(b)
-	mac := hmac.New(sha1.New, []byte(o.s3.Secret))
+	key, secret := o.s3.credentials()
+	mac := hmac.New(sha1.New, []byte(secret))
 	mac.Write([]byte(policy64))
 
 	uv := make(url.Values)
-	uv.Set("AWSAccessKeyId", o.s3.AccessKey)
+	uv.Set("AWSAccessKeyId", key)
 	uv.Set("acl", string(acl))
 	uv.Set("key", o.Key())
 	uv.Set("signature", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
@@ -169,7 +303,12 @@ func (o *object) FormURL(acl ACL, policy Policy, query ...url.Values) (*url.URL,
 		}
 	}
 
-	u, err := url.Parse(s3proto + `://` + o.s3.Bucket + `.` + s3host)
+	// custom endpoints may not support virtual hosted buckets
+	formURL := s3proto + `://` + o.s3.Bucket + `.` + s3host
+	if c := o.s3.client(); c.Endpoint != "" {
+		formURL = c.endpoint() + `/` + o.s3.Bucket
+	}
+	u, err := url.Parse(formURL)
 	if err != nil {
 		return nil, err
 	}
@@ -178,24 +317,24 @@ func (o *object) FormURL(acl ACL, policy Policy, query ...url.Values) (*url.URL,
 	return u, nil
 }
 
-func (o *object) request(method string, code int, serr string) (*http.Response, error) {
-	req, err := http.NewRequest(method, o.url(""), nil)
+func (o *object) request(method, query string, h http.Header, code int, serr string) (*http.Response, error) {
+	req, err := http.NewRequest(method, o.url(query), nil)
 	if err != nil {
 		return nil, err
 	}
-
-	o.s3.signRequest(req)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
+	for k, v := range h {
+		req.Header[k] = v
 	}
 
-	if c := resp.StatusCode; code > 0 && c != code {
-		return nil, fmt.Errorf("s3: %s (%s)", serr, http.StatusText(c))
-	}
+	return o.s3.do(req, code, serr)
+}
 
-	return resp, nil
+// contentType guesses the content type from the key extension
+func (o *object) contentType() string {
+	if v, ok := mimeTypes[filepath.Ext(o.key)]; ok {
+		return v
+	}
+	return "application/octet-stream"
 }
 
 func (o *object) resource(query string) string {
@@ -203,9 +342,24 @@ func (o *object) resource(query string) string {
 }
 
 func (o *object) url(query string) string {
-	return s3proto + `://` + s3host + o.resource(query)
+	return o.s3.endpoint() + o.resource(query)
 }
 
 func trim(s string) string {
 	return strings.Trim(s, ` /`)
 }
+
+// trimETag trims outer space and quotes from an etag
+func trimETag(s string) string {
+	return strings.Trim(s, ` "`)
+}
+
+// plainObject returns the object to address requests to o, which may be
+// wrapped, e.g. by EncryptedObject.
+func plainObject(o Object) *object {
+	if p, ok := o.(*object); ok {
+		return p
+	}
+	s3 := o.S3()
+	return s3.raw(o.Key())
+}
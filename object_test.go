@@ -2,8 +2,10 @@ package s3
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
@@ -136,6 +138,354 @@ func TestS3(t *testing.T) {
 	}
 }
 
+func TestConditionalPut(t *testing.T) {
+	o := s3.Object(fmt.Sprintf("%d/lock.txt", time.Now().UnixNano()))
+	defer o.Delete()
+
+	// create only
+	create := WriteOptions{IfNoneMatch: "*"}
+	h, err := o.Put(strings.NewReader("a"), create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.Put(strings.NewReader("b"), create)
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatal(err)
+	}
+
+	// compare and swap
+	_, err = o.Put(strings.NewReader("c"), WriteOptions{IfMatch: h.ETag()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.Put(strings.NewReader("d"), WriteOptions{IfMatch: h.ETag()})
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatal(err)
+	}
+
+	// multipart
+	w := o.Writer(create)
+	io.Copy(w, strings.NewReader("e"))
+	if err := w.Close(); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatal(err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	prefix := fmt.Sprintf("%d", time.Now().UnixNano())
+	src := s3.Object(prefix + "/src.txt")
+	dst := s3.Object(prefix + "/dst.txt")
+	defer src.Delete()
+	defer dst.Delete()
+
+	h, err := src.Put(strings.NewReader("copy me"), WriteOptions{Metadata: map[string]string{"a": "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := src.CopyTo(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := res.ETag; x != trimETag(h.ETag()) {
+		t.Fatal(x)
+	}
+	dh, err := dst.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := dh.Metadata()["a"]; x != "1" {
+		t.Fatal(x)
+	}
+
+	// replace metadata
+	_, err = src.CopyTo(dst, CopyOptions{
+		MetadataDirective: ReplaceMetadata,
+		WriteOptions:      WriteOptions{Metadata: map[string]string{"b": "2"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dh, err = dst.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := dh.Metadata(); m["a"] != "" || m["b"] != "2" {
+		t.Fatal(m)
+	}
+
+	// failed source condition
+	_, err = src.CopyTo(dst, CopyOptions{SourceIfMatch: "nope"})
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatal(err)
+	}
+}
+
+func TestMove(t *testing.T) {
+	prefix := fmt.Sprintf("%d", time.Now().UnixNano())
+	for _, k := range []string{"a/1.txt", "a/2.txt", "a/b/3.txt"} {
+		if _, err := s3.Object(prefix + "/" + k).Put(strings.NewReader(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// single object
+	dst := s3.Object(prefix + "/c/1.txt")
+	if err := s3.Object(prefix + "/a/1.txt").MoveTo(dst); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s3.Object(prefix + "/a/1.txt").Exists(); ok {
+		t.Fatal(ok)
+	}
+	if ok, _ := dst.Exists(); !ok {
+		t.Fatal(ok)
+	}
+
+	// prefix
+	var progress int
+	report, err := s3.MovePrefix(prefix+"/a/", prefix+"/c/", MoveOptions{
+		Progress: func(src, dst string, size int64, err error) {
+			progress++
+		},
+		Concurrency: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Moved != 2 || report.Bytes != 16 || progress != 2 {
+		t.Fatal(report, progress)
+	}
+	for _, k := range []string{"c/1.txt", "c/2.txt", "c/b/3.txt"} {
+		o := s3.Object(prefix + "/" + k)
+		if ok, _ := o.Exists(); !ok {
+			t.Fatal(k)
+		}
+		o.Delete()
+	}
+}
+
+func TestList(t *testing.T) {
+	prefix := fmt.Sprintf("%d", time.Now().UnixNano())
+	keys := []string{"a.txt", "b/1.txt", "b/2.txt", "c.txt"}
+	for _, k := range keys {
+		o := s3.Object(prefix + "/" + k)
+		if _, err := o.Put(strings.NewReader(k)); err != nil {
+			t.Fatal(err)
+		}
+		defer o.Delete()
+	}
+
+	// small pages
+	var got []string
+	it := s3.List(ListOptions{Prefix: prefix + "/", MaxKeys: 1})
+	for it.Next() {
+		got = append(got, it.Entry().Key)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if x := strings.Join(got, ","); x != prefix+"/a.txt,"+prefix+"/b/1.txt,"+prefix+"/b/2.txt,"+prefix+"/c.txt" {
+		t.Fatal(x)
+	}
+
+	// delimited
+	got = nil
+	it = s3.List(ListOptions{Prefix: prefix + "/", Delimiter: "/", StartAfter: prefix + "/a.txt"})
+	for it.Next() {
+		got = append(got, it.Entry().Key)
+	}
+	if x := strings.Join(got, ","); x != prefix+"/b/,"+prefix+"/c.txt" {
+		t.Fatal(x)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	prefix := fmt.Sprintf("%d", time.Now().UnixNano())
+	for _, k := range []string{"a.txt", "b/1.txt", "b/c/2.txt", "d/3.txt", "e.txt"} {
+		o := s3.Object(prefix + "/" + k)
+		if _, err := o.Put(strings.NewReader(k)); err != nil {
+			t.Fatal(err)
+		}
+		defer o.Delete()
+	}
+
+	var got []string
+	err := s3.Walk(prefix, func(key string, e ListEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		got = append(got, strings.TrimPrefix(key, prefix+"/"))
+		switch key {
+		case prefix + "/d/":
+			return fs.SkipDir
+		case prefix + "/e.txt":
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := strings.Join(got, ","); x != ",a.txt,b/,b/1.txt,b/c/,b/c/2.txt,d/,e.txt" {
+		t.Fatal(x)
+	}
+}
+
+func TestDeleteObjects(t *testing.T) {
+	prefix := fmt.Sprintf("%d", time.Now().UnixNano())
+	var keys []string
+	for i := 0; i < 3; i++ {
+		k := fmt.Sprintf("%s/%d.txt", prefix, i)
+		if _, err := s3.Object(k).Put(strings.NewReader(k)); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, k)
+	}
+
+	failed, err := s3.DeleteObjects(append(keys, prefix+"/doesnotexist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) > 0 {
+		t.Fatal(failed)
+	}
+	for _, k := range keys {
+		if ok, _ := s3.Object(k).Exists(); ok {
+			t.Fatal(k)
+		}
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	prefix := fmt.Sprintf("%d/", time.Now().UnixNano())
+	for i := 0; i < 3; i++ {
+		k := fmt.Sprintf("%s%d.txt", prefix, i)
+		if _, err := s3.Object(k).Put(strings.NewReader("abc")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := s3.DeletePrefix(prefix, DeletePrefixOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Deleted != 3 || report.Bytes != 9 {
+		t.Fatal(report)
+	}
+	if ok, _ := s3.Object(prefix + "0.txt").Exists(); !ok {
+		t.Fatal("dry run deleted")
+	}
+
+	report, err = s3.DeletePrefix(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Deleted != 3 || report.Bytes != 9 {
+		t.Fatal(report)
+	}
+	if ok, _ := s3.Object(prefix + "0.txt").Exists(); ok {
+		t.Fatal("not deleted")
+	}
+}
+
+func TestVersions(t *testing.T) {
+	vc, err := s3.GetVersioning()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vc.Status != VersioningEnabled {
+		t.Skip("bucket isn't versioned")
+	}
+
+	o := s3.Object(fmt.Sprintf("%d/versioned.txt", time.Now().UnixNano()))
+	h1, err := o.Put(strings.NewReader("one"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, err := o.Put(strings.NewReader("two"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, _, err := o.Reader(ReadOptions{VersionID: h1.VersionID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "one" {
+		t.Fatal(string(b))
+	}
+
+	if err := o.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	var versions []ObjectVersion
+	it := s3.ListObjectVersions(VersionListOptions{Prefix: o.Key()})
+	for it.Next() {
+		versions = append(versions, it.Version())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 || !versions[0].IsDeleteMarker || !versions[0].IsLatest {
+		t.Fatal(versions)
+	}
+
+	// removes the delete marker
+	if err := o.RestoreVersion(h2.VersionID()); err != nil {
+		t.Fatal(err)
+	}
+	if h, err := o.Head(); err != nil || h.VersionID() != h2.VersionID() {
+		t.Fatal(h, err)
+	}
+
+	// copies the first version on top
+	if err := o.RestoreVersion(h1.VersionID()); err != nil {
+		t.Fatal(err)
+	}
+	if versions, err = o.Versions(); err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 || versions[0].IsDeleteMarker || versions[0].Size != 3 {
+		t.Fatal(versions)
+	}
+
+	for _, v := range versions {
+		if err := o.Delete(DeleteOptions{VersionID: v.VersionID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	buckets, err := s3.ListBuckets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, b := range buckets {
+		found = found || b.Name == s3.Bucket
+	}
+	if !found {
+		t.Fatal(buckets)
+	}
+
+	if _, err := s3.HeadBucket(); err != nil {
+		t.Fatal(err)
+	}
+	region, err := s3.GetBucketLocation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if region == "" {
+		t.Fatal("no region")
+	}
+}
+
 func TestFormURL(t *testing.T) {
 	fileName := "ü n i c ö d e.txt"
 	content := "form"
@@ -0,0 +1,182 @@
+package s3
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteOptions configure uploads done with Writer or Put. The zero value
+// uploads the object unconditionally.
+type WriteOptions struct {
+	// IfNoneMatch makes the write succeed only if no object exists under the key.
+	// S3 only supports the value "*".
+	IfNoneMatch string
+
+	// IfMatch makes the write succeed only if the current object has this ETag.
+	IfMatch string
+
+	// Metadata is stored as user metadata with the object. The keys are sent
+	// as x-amz-meta-* headers.
+	Metadata map[string]string
+
+	// ContentType overrides the content type guessed from the key extension.
+	ContentType string
+
+	// ACL is the canned ACL applied to the object. If empty, the bucket default
+	// applies.
+	ACL ACL
+
+	// Explicit grants, each a comma separated list of grantees in the form
+	// id="...", emailAddress="..." or uri="...". They can't be combined with ACL.
+	GrantRead        string
+	GrantReadACP     string
+	GrantWriteACP    string
+	GrantFullControl string
+
+	// StorageClass of the object. If empty, S3 stores it as Standard.
+	StorageClass StorageClass
+
+	// Encryption enables server side encryption
+	Encryption *Encryption
+
+	// Compression compresses the data on the fly. The algorithm is recorded in
+	// the Content-Encoding header, unless ContentEncoding is set, and in the
+	// metadata so ReadOptions.Decompress can undo it.
+	Compression Compression
+
+	// Standard HTTP headers that S3 stores and returns on reads
+	CacheControl       string
+	ContentDisposition string
+	ContentEncoding    string
+	ContentLanguage    string
+	Expires            time.Time
+}
+
+// writeOptions returns the first options value, or the zero value if none was
+// passed.
+func writeOptions(opts []WriteOptions) WriteOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return WriteOptions{}
+}
+
+// setConditions adds the conditional write headers to h. For multipart uploads
+// they belong on the complete request.
+func (wo *WriteOptions) setConditions(h http.Header) {
+	if wo.IfNoneMatch != "" {
+		h.Set("If-None-Match", wo.IfNoneMatch)
+	}
+	if wo.IfMatch != "" {
+		h.Set("If-Match", wo.IfMatch)
+	}
+}
+
+// setHeaders adds the object headers to h. They are sent with the single PUT or
+// when a multipart upload is initiated.
+func (wo *WriteOptions) setHeaders(h http.Header) {
+	if wo.ContentType != "" {
+		h.Set("Content-Type", wo.ContentType)
+	}
+	for k, v := range wo.Metadata {
+		h.Set(metaPrefix+strings.ToLower(k), v)
+	}
+	if wo.Compression != "" {
+		h.Set("Content-Encoding", string(wo.Compression))
+		h.Set(metaPrefix+metaCompression, string(wo.Compression))
+	}
+	for k, v := range map[string]string{
+		"x-amz-acl":                string(wo.ACL),
+		"x-amz-storage-class":      string(wo.StorageClass),
+		"x-amz-grant-read":         wo.GrantRead,
+		"x-amz-grant-read-acp":     wo.GrantReadACP,
+		"x-amz-grant-write-acp":    wo.GrantWriteACP,
+		"x-amz-grant-full-control": wo.GrantFullControl,
+		"Cache-Control":            wo.CacheControl,
+		"Content-Disposition":      wo.ContentDisposition,
+		"Content-Encoding":         wo.ContentEncoding,
+		"Content-Language":         wo.ContentLanguage,
+	} {
+		if v != "" {
+			h.Set(k, v)
+		}
+	}
+	if !wo.Expires.IsZero() {
+		h.Set("Expires", wo.Expires.UTC().Format(http.TimeFormat))
+	}
+	wo.Encryption.setHeaders(h)
+}
+
+// ReadOptions configure Reader and Head. The zero value reads the object
+// without any additional headers.
+type ReadOptions struct {
+	// Encryption must carry the customer key if the object was written with
+	// SSE-C.
+	Encryption *Encryption
+
+	// Offset and Length restrict Reader to a byte range of the object. A zero
+	// Length reads up to the end.
+	Offset int64
+	Length int64
+
+	// VersionID reads a specific version of the object instead of the latest
+	VersionID string
+
+	// IfMatch fails the read with ErrPreconditionFailed unless the object has
+	// this ETag
+	IfMatch string
+
+	// Decompress makes Reader undo the compression of objects written with
+	// WriteOptions.Compression or a Content-Encoding of a registered codec.
+	Decompress bool
+}
+
+// readOptions returns the first options value, or the zero value if none was
+// passed.
+func readOptions(opts []ReadOptions) ReadOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return ReadOptions{}
+}
+
+// header returns the request header for reading the object
+func (ro *ReadOptions) header() http.Header {
+	h := make(http.Header)
+	ro.Encryption.setCustomerHeaders(h, ssePrefix)
+	if ro.IfMatch != "" {
+		h.Set("If-Match", ro.IfMatch)
+	}
+	// keep the http transport from decompressing gzip on its own, the content
+	// is returned as stored unless Decompress is set
+	h.Set("Accept-Encoding", "identity")
+	return h
+}
+
+func (ro *ReadOptions) ranged() bool {
+	return ro.Offset > 0 || ro.Length > 0
+}
+
+// setRange adds the Range header for a ranged read to h
+func (ro *ReadOptions) setRange(h http.Header) {
+	if !ro.ranged() {
+		return
+	}
+	r := "bytes=" + strconv.FormatInt(ro.Offset, 10) + "-"
+	if ro.Length > 0 {
+		r += strconv.FormatInt(ro.Offset+ro.Length-1, 10)
+	}
+	h.Set("Range", r)
+}
+
+// DeleteOptions configure Object.Delete
+type DeleteOptions struct {
+	// VersionID permanently deletes a specific version of the object
+	VersionID string
+
+	// MFA is the serial number of the MFA device, a space and the current code.
+	// It is required to delete versions if MFA delete is enabled on the bucket.
+	MFA string
+}
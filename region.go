@@ -0,0 +1,158 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxRedirects is the number of redirects followed by a single request
+const maxRedirects = 5
+
+// regionEndpoint returns the endpoint of a region. us-east-1 and unknown
+// regions use the global endpoint.
+func regionEndpoint(region string) string {
+	if region == "" || region == "us-east-1" {
+		return s3proto + `://` + s3host
+	}
+	return s3proto + `://s3.` + region + `.amazonaws.com`
+}
+
+// send sends a signed request. If the bucket lives in another region, the
+// region is cached and the request is re-signed with sign, which then uses
+// Signature Version 4 for that region, and sent to the regional endpoint.
+// Temporary redirects are followed path style and re-signed as well, since
+// version 4 signs the host.
+func (s3 *S3) send(req *http.Request, sign func()) (*http.Response, error) {
+	c := s3.client()
+
+	// the http client would drop the authorization on redirects to other hosts
+	hc := *c.httpClient()
+	hc.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	for i := 0; ; i++ {
+		resp, err := hc.Do(req)
+		if err != nil || i == maxRedirects {
+			return resp, err
+		}
+
+		var u *url.URL
+		switch resp.StatusCode {
+		case http.StatusTemporaryRedirect:
+			if u, err = resp.Location(); err != nil {
+				return resp, nil
+			}
+			u = s3.pathStyle(u)
+		case http.StatusMovedPermanently, http.StatusBadRequest:
+			if c.Endpoint != "" || s3.Bucket == "" {
+				return resp, nil
+			}
+			region := s3.redirectRegion(req, resp)
+			if region == "" || region == c.region(s3.Bucket) {
+				return resp, nil
+			}
+			c.setRegion(s3.Bucket, region)
+			if u, err = url.Parse(regionEndpoint(region)); err != nil {
+				return resp, nil
+			}
+			uu := *req.URL
+			uu.Scheme, uu.Host = u.Scheme, u.Host
+			u = &uu
+		default:
+			return resp, nil
+		}
+
+		if !rewind(req) {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		req.URL = u
+		req.Host = ""
+		sign()
+	}
+}
+
+// pathStyle turns a virtual hosted URL of the bucket into a path style URL on
+// the same host, so the bucket stays part of the signed resource.
+func (s3 *S3) pathStyle(u *url.URL) *url.URL {
+	if s3.Bucket == "" || !strings.HasPrefix(u.Host, s3.Bucket+".") {
+		return u
+	}
+	uu := *u
+	uu.Host = strings.TrimPrefix(u.Host, s3.Bucket+".")
+	uu.Path = "/" + s3.Bucket + "/" + strings.TrimPrefix(u.Path, "/")
+	uu.RawPath = ""
+	return &uu
+}
+
+// redirectRegion returns the region of the bucket from a 301 PermanentRedirect,
+// a 400 AuthorizationHeaderMalformed or a 400 InvalidRequest response with the
+// bucket region header, or an empty string if resp is another error. The body
+// of resp is kept for reading the error.
+func (s3 *S3) redirectRegion(req *http.Request, resp *http.Response) string {
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	var e struct {
+		Code   string
+		Region string
+	}
+	xml.Unmarshal(b, &e)
+	if resp.StatusCode == http.StatusBadRequest && e.Code != "AuthorizationHeaderMalformed" {
+		// version 2 requests to regions only accepting version 4
+		if e.Code != "InvalidRequest" {
+			return ""
+		}
+		return resp.Header.Get("x-amz-bucket-region")
+	}
+	if e.Region != "" {
+		return e.Region
+	}
+	if r := resp.Header.Get("x-amz-bucket-region"); r != "" {
+		return r
+	}
+
+	// ask the global endpoint, unless this already is the location request
+	if _, ok := req.URL.Query()["location"]; ok {
+		return ""
+	}
+	region, err := s3.globalLocation()
+	if err != nil {
+		return ""
+	}
+	return region
+}
+
+// globalLocation asks the global endpoint for the bucket location. The request
+// goes through a client without cached regions, so requests in flight keep
+// the cached region until send replaces it.
+func (s3 *S3) globalLocation() (string, error) {
+	c := s3.client()
+	g := *s3
+	g.Client = &Client{AccessKey: c.AccessKey, Secret: c.Secret, HTTPClient: c.HTTPClient}
+	return g.GetBucketLocation()
+}
+
+// rewind resets the body of req for sending it again. It reports false if the
+// body can't be read again.
+func rewind(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return true
+	}
+	if req.GetBody == nil {
+		return false
+	}
+	b, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	req.Body = b
+	return true
+}
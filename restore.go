@@ -0,0 +1,91 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+type StorageClass string
+
+const (
+	Standard           StorageClass = "STANDARD"
+	ReducedRedundancy  StorageClass = "REDUCED_REDUNDANCY"
+	StandardIA         StorageClass = "STANDARD_IA"
+	OnezoneIA          StorageClass = "ONEZONE_IA"
+	IntelligentTiering StorageClass = "INTELLIGENT_TIERING"
+	Glacier            StorageClass = "GLACIER"
+	GlacierIR          StorageClass = "GLACIER_IR"
+	DeepArchive        StorageClass = "DEEP_ARCHIVE"
+)
+
+// Tier is the retrieval tier used to restore archived objects
+type Tier string
+
+const (
+	TierExpedited Tier = "Expedited"
+	TierStandard  Tier = "Standard"
+	TierBulk      Tier = "Bulk"
+)
+
+// RestoreStatus is the state of a restore request as reported in the
+// x-amz-restore header.
+type RestoreStatus struct {
+	// Ongoing is true while the object is being restored
+	Ongoing bool
+
+	// Expiry is the time the restored copy will be removed again. It is only
+	// set once the restore finished.
+	Expiry time.Time
+}
+
+var restoreRx = regexp.MustCompile(`([a-z-]+)="([^"]*)"`)
+
+func parseRestoreStatus(s string) (*RestoreStatus, error) {
+	rs := new(RestoreStatus)
+	for _, m := range restoreRx.FindAllStringSubmatch(s, -1) {
+		switch m[1] {
+		case "ongoing-request":
+			rs.Ongoing = (m[2] == "true")
+		case "expiry-date":
+			t, err := time.Parse(time.RFC1123, m[2])
+			if err != nil {
+				return nil, err
+			}
+			rs.Expiry = t
+		}
+	}
+	return rs, nil
+}
+
+func (o *object) Restore(days int, tier Tier) error {
+	var v struct {
+		XMLName xml.Name `xml:"RestoreRequest"`
+		Days    int
+		Tier    Tier `xml:"GlacierJobParameters>Tier,omitempty"`
+	}
+	v.Days = days
+	v.Tier = tier
+
+	b, err := xml.Marshal(&v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", o.url("?restore"), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	resp, err := o.s3.do(req, 0, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 202 starts a new restore, 200 extends the expiry of a restored copy
+	if c := resp.StatusCode; c != 200 && c != 202 {
+		return newS3Error(resp, "s3: error restoring object (%s)", http.StatusText(c))
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package s3
 
 import (
 	"crypto/hmac"
+	"crypto/md5"
 	"crypto/sha1"
 	"encoding/base64"
 	"fmt"
@@ -25,12 +26,51 @@ type S3 struct {
 
 	// Path is the path to prepend to all keys
 	Path string
+
+	// Client provides the endpoint and HTTP client, and the credentials if
+	// AccessKey is empty. Nil uses the defaults. See Client.Bucket.
+	Client *Client
 }
 
 func (s3 *S3) Object(key string) Object {
 	return &object{key: key, s3: *s3}
 }
 
+// prefix returns the bucket key prefix for a prefix relative to Path. Unlike
+// keys, prefixes keep a trailing slash.
+func (s3 *S3) prefix(p string) string {
+	p = strings.TrimLeft(p, ` /`)
+	if path := trim(s3.Path); path != "" {
+		return path + `/` + p
+	}
+	return p
+}
+
+// relKey strips Path from a bucket key
+func (s3 *S3) relKey(key string) string {
+	if path := trim(s3.Path); path != "" {
+		return strings.TrimPrefix(key, path+`/`)
+	}
+	return key
+}
+
+// raw returns an object for a bucket key, which doesn't get Path prepended
+func (s3 *S3) raw(key string) *object {
+	c := *s3
+	c.Path = ""
+	return c.exactObject(key)
+}
+
+// exactObject returns an object for a key relative to Path, which is used as is
+// rather than trimmed like keys passed to Object. Keys from listings need this.
+func (s3 *S3) exactObject(key string) *object {
+	return &object{key: key, s3: *s3, exact: true}
+}
+
+func (s3 *S3) bucketURL(query string) string {
+	return s3.endpoint() + `/` + s3.Bucket + `/` + query
+}
+
 // http://docs.aws.amazon.com/AmazonS3/latest/dev/RESTAuthentication.html
 func (s3 *S3) authString(req *http.Request) string {
 	if req.Header.Get("Date") == "" {
@@ -110,13 +150,62 @@ func escape(s string) string {
 	return strings.Replace(url.QueryEscape(s), `+`, `%20`, -1)
 }
 
+// contentMD5 returns the Content-MD5 header value for a request body
+func contentMD5(b []byte) string {
+	sum := md5.Sum(b)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// do signs and sends the request. If code is greater than zero, any other
+// response status is turned into an error.
+func (s3 *S3) do(req *http.Request, code int, serr string) (*http.Response, error) {
+	return s3.doParams(req, nil, code, serr)
+}
+
+// doParams is like do, but adds request parameters like a listing prefix to the
+// query. Version 2 signatures only cover subresources like ?acl or ?uploadId,
+// so params are added after signing, while version 4 signs all of them.
+func (s3 *S3) doParams(req *http.Request, params url.Values, code int, serr string) (*http.Response, error) {
+	query := req.URL.RawQuery
+	sign := func() {
+		req.URL.RawQuery = query
+		region := s3.signingRegion()
+		if region == "" {
+			s3.signRequest(req)
+		}
+		if len(params) > 0 {
+			if req.URL.RawQuery != "" {
+				req.URL.RawQuery += "&"
+			}
+			req.URL.RawQuery += params.Encode()
+		}
+		if region != "" {
+			s3.signRequestV4(req, region, time.Now())
+		}
+	}
+	sign()
+
+	resp, err := s3.send(req, sign)
+	if err != nil {
+		return nil, err
+	}
+
+	if c := resp.StatusCode; code > 0 && c != code {
+		defer resp.Body.Close()
+		return nil, newS3Error(resp, "s3: %s (%s)", serr, http.StatusText(c))
+	}
+
+	return resp, nil
+}
+
 func (s3 *S3) signRequest(req *http.Request) {
 	authStr := s3.authString(req)
 
-	h := hmac.New(sha1.New, []byte(s3.Secret))
+	key, secret := s3.credentials()
+	h := hmac.New(sha1.New, []byte(secret))
 	h.Write([]byte(authStr))
 
 	h64 := base64.StdEncoding.EncodeToString(h.Sum(nil))
-	auth := "AWS " + s3.AccessKey + ":" + h64
+	auth := "AWS " + key + ":" + h64
 	req.Header.Set("Authorization", auth)
 }
@@ -0,0 +1,189 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+
+	// unsignedPayload leaves the body out of the signature, S3 accepts it over
+	// https
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// signingRegion returns the region requests are signed for with Signature
+// Version 4, or an empty string for version 2. Version 4 is used once the
+// bucket region is known, since regions launched after 2014 only accept it.
+func (s3 *S3) signingRegion() string {
+	c := s3.client()
+	if c.Endpoint != "" || s3.Bucket == "" {
+		return ""
+	}
+	return c.region(s3.Bucket)
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (s3 *S3) signRequestV4(req *http.Request, region string, t time.Time) {
+	amzDate := t.UTC().Format(sigV4TimeFormat)
+	req.Header.Set("x-amz-date", amzDate)
+	if req.Header.Get("x-amz-content-sha256") == "" {
+		req.Header.Set("x-amz-content-sha256", unsignedPayload)
+	}
+
+	// send the path and query exactly as signed
+	uri := escapeV4(req.URL.Path, false)
+	if uri == "" {
+		uri = "/"
+	}
+	req.URL.RawPath = uri
+	req.URL.RawQuery = canonicalQueryV4(req.URL.Query())
+
+	names, headers := canonicalHeadersV4(req)
+	creq := strings.Join([]string{
+		req.Method,
+		uri,
+		req.URL.RawQuery,
+		headers,
+		names,
+		req.Header.Get("x-amz-content-sha256"),
+	}, "\n")
+
+	key, _ := s3.credentials()
+	scope := amzDate[:8] + "/" + region + "/s3/aws4_request"
+	sig := s3.signatureV4(creq, region, amzDate)
+
+	req.Header.Set("Authorization", sigV4Algorithm+" Credential="+key+"/"+scope+
+		",SignedHeaders="+names+",Signature="+sig)
+}
+
+// presignV4 adds the query string authentication for a GET of u, valid for
+// expiresIn from t. Only the host header is signed, so the URL works in any
+// client.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
+func (s3 *S3) presignV4(u *url.URL, region string, t time.Time, expiresIn time.Duration) {
+	amzDate := t.UTC().Format(sigV4TimeFormat)
+	key, _ := s3.credentials()
+
+	q := u.Query()
+	q.Set("X-Amz-Algorithm", sigV4Algorithm)
+	q.Set("X-Amz-Credential", key+"/"+amzDate[:8]+"/"+region+"/s3/aws4_request")
+	q.Set("X-Amz-Date", amzDate)
+	q.Set("X-Amz-Expires", strconv.FormatInt(int64(expiresIn/time.Second), 10))
+	q.Set("X-Amz-SignedHeaders", "host")
+
+	uri := escapeV4(u.Path, false)
+	if uri == "" {
+		uri = "/"
+	}
+	u.RawPath = uri
+	u.RawQuery = canonicalQueryV4(q)
+
+	creq := strings.Join([]string{
+		"GET",
+		uri,
+		u.RawQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	u.RawQuery += "&X-Amz-Signature=" + s3.signatureV4(creq, region, amzDate)
+}
+
+// signatureV4 signs the canonical request creq for the region at amzDate.
+func (s3 *S3) signatureV4(creq, region, amzDate string) string {
+	scope := amzDate[:8] + "/" + region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(creq))
+	toSign := strings.Join([]string{sigV4Algorithm, amzDate, scope, hex.EncodeToString(sum[:])}, "\n")
+
+	_, secret := s3.credentials()
+	k := hmacSHA256([]byte("AWS4"+secret), amzDate[:8])
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, "s3")
+	k = hmacSHA256(k, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(k, toSign))
+}
+
+// canonicalHeadersV4 returns the names of the signed headers and their
+// canonical form. The host, the content headers and all x-amz headers are
+// signed.
+func canonicalHeadersV4(req *http.Request) (names, headers string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	h := map[string]string{"host": host}
+	for k, vv := range req.Header {
+		k = strings.ToLower(k)
+		switch {
+		case k == "content-md5", k == "content-type", k == "range", strings.HasPrefix(k, "x-amz-"):
+		default:
+			continue
+		}
+		v := make([]string, len(vv))
+		for i := range vv {
+			v[i] = strings.Join(strings.Fields(vv[i]), " ")
+		}
+		h[k] = strings.Join(v, ",")
+	}
+
+	a := make([]string, 0, len(h))
+	for k := range h {
+		a = append(a, k)
+	}
+	sort.Strings(a)
+
+	var b strings.Builder
+	for _, k := range a {
+		b.WriteString(k + ":" + h[k] + "\n")
+	}
+	return strings.Join(a, ";"), b.String()
+}
+
+// canonicalQueryV4 sorts and escapes all query parameters. Unlike version 2,
+// plain request parameters are signed as well.
+func canonicalQueryV4(query url.Values) string {
+	a := make([]string, 0, len(query))
+	for k, vv := range query {
+		for _, v := range vv {
+			a = append(a, escapeV4(k, true)+"="+escapeV4(v, true))
+		}
+	}
+	sort.Strings(a)
+	return strings.Join(a, "&")
+}
+
+// escapeV4 percent encodes everything but unreserved characters, and slashes
+// unless encodeSlash is set.
+func escapeV4(s string, encodeSlash bool) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
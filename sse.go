@@ -0,0 +1,75 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"net/http"
+)
+
+// Server side encryption algorithms
+const (
+	SSES3  = "AES256"
+	SSEKMS = "aws:kms"
+)
+
+// SSE-C header prefixes for the object and the source of a copy
+const (
+	ssePrefix           = "x-amz-server-side-encryption-customer-"
+	sseCopySourcePrefix = "x-amz-copy-source-server-side-encryption-customer-"
+)
+
+// Encryption configures server side encryption. Set Algorithm to use keys
+// managed by S3 (SSES3) or KMS (SSEKMS), or CustomerKey to encrypt with a key
+// provided by the caller (SSE-C). An SSE-C key has to be passed again for every
+// read of the object.
+type Encryption struct {
+	Algorithm string
+
+	// KMSKeyID is the KMS key to use with SSEKMS. If empty, the AWS managed
+	// key of the account is used.
+	KMSKeyID string
+
+	// KMSContext is the base64 encoded JSON encryption context for SSEKMS
+	KMSContext string
+
+	// CustomerKey is the 256 bit SSE-C key
+	CustomerKey []byte
+}
+
+// setHeaders adds the headers for writing an encrypted object to h
+func (e *Encryption) setHeaders(h http.Header) {
+	if e == nil {
+		return
+	}
+	if e.Algorithm != "" {
+		h.Set("x-amz-server-side-encryption", e.Algorithm)
+	}
+	if e.KMSKeyID != "" {
+		h.Set("x-amz-server-side-encryption-aws-kms-key-id", e.KMSKeyID)
+	}
+	if e.KMSContext != "" {
+		h.Set("x-amz-server-side-encryption-context", e.KMSContext)
+	}
+	e.setCustomerHeaders(h, ssePrefix)
+}
+
+// setCopySourceHeaders adds the SSE-C headers needed to read the source of a
+// server side copy.
+func (e *Encryption) setCopySourceHeaders(h http.Header) {
+	if e == nil {
+		return
+	}
+	e.setCustomerHeaders(h, sseCopySourcePrefix)
+}
+
+// setCustomerHeaders adds the SSE-C headers, which are needed on every request
+// touching the object data.
+func (e *Encryption) setCustomerHeaders(h http.Header, prefix string) {
+	if e == nil || len(e.CustomerKey) == 0 {
+		return
+	}
+	sum := md5.Sum(e.CustomerKey)
+	h.Set(prefix+"algorithm", SSES3)
+	h.Set(prefix+"key", base64.StdEncoding.EncodeToString(e.CustomerKey))
+	h.Set(prefix+"key-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+}
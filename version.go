@@ -0,0 +1,334 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	errVersionNotFound     = errors.New("s3: version not found")
+	errRestoreDeleteMarker = errors.New("s3: can't restore a delete marker")
+)
+
+// VersioningStatus is the versioning state of a bucket. Buckets that never had
+// versioning enabled have an empty status.
+type VersioningStatus string
+
+const (
+	VersioningEnabled   VersioningStatus = "Enabled"
+	VersioningSuspended VersioningStatus = "Suspended"
+)
+
+// MFADeleteStatus tells whether deleting versions and changing the versioning
+// state requires MFA.
+type MFADeleteStatus string
+
+const (
+	MFADeleteEnabled  MFADeleteStatus = "Enabled"
+	MFADeleteDisabled MFADeleteStatus = "Disabled"
+)
+
+// VersioningConfiguration is the versioning configuration of a bucket
+type VersioningConfiguration struct {
+	XMLName   xml.Name         `xml:"VersioningConfiguration"`
+	Status    VersioningStatus `xml:",omitempty"`
+	MFADelete MFADeleteStatus  `xml:",omitempty"`
+
+	// MFA is the serial number of the MFA device, a space and the current code.
+	// It is required by PutVersioning to change MFADelete.
+	MFA string `xml:"-"`
+}
+
+// GetVersioning returns the versioning configuration of the bucket
+func (s3 *S3) GetVersioning() (*VersioningConfiguration, error) {
+	req, err := http.NewRequest("GET", s3.bucketURL("?versioning"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s3.do(req, 200, "error getting versioning")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	vc := new(VersioningConfiguration)
+	if err := xml.NewDecoder(resp.Body).Decode(vc); err != nil {
+		return nil, err
+	}
+	return vc, nil
+}
+
+// PutVersioning sets the versioning configuration of the bucket. Versioning
+// can't be disabled once enabled, only suspended.
+func (s3 *S3) PutVersioning(vc *VersioningConfiguration) error {
+	b, err := xml.Marshal(vc)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("PUT", s3.bucketURL("?versioning"), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-MD5", contentMD5(b))
+	if vc.MFA != "" {
+		req.Header.Set("x-amz-mfa", vc.MFA)
+	}
+
+	resp, err := s3.do(req, 200, "error putting versioning")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// VersionListOptions configure a version listing. Prefix and KeyMarker are
+// relative to Path.
+type VersionListOptions struct {
+	Prefix string
+
+	// KeyMarker and VersionIDMarker start the listing after this version. With
+	// only KeyMarker, the listing starts after all versions of the key.
+	KeyMarker       string
+	VersionIDMarker string
+
+	// MaxKeys is the number of versions fetched per page. S3 returns at most
+	// 1000.
+	MaxKeys int
+}
+
+// ObjectVersion is a version or delete marker of a key. Keys are relative to
+// Path.
+type ObjectVersion struct {
+	Key            string
+	VersionID      string
+	IsLatest       bool
+	IsDeleteMarker bool
+	LastModified   time.Time
+	ETag           string
+	Size           int64
+	StorageClass   StorageClass
+	Owner          *Owner
+}
+
+// VersionIterator lazily fetches the pages of a version listing. Versions are
+// returned in key order, and newest first for each key.
+type VersionIterator struct {
+	s3      *S3
+	params  url.Values
+	page    []ObjectVersion
+	version ObjectVersion
+	done    bool
+	err     error
+}
+
+// ListObjectVersions returns an iterator over all versions and delete markers
+// in the bucket
+func (s3 *S3) ListObjectVersions(opts VersionListOptions) *VersionIterator {
+	params := make(url.Values)
+	if p := s3.prefix(opts.Prefix); p != "" {
+		params.Set("prefix", p)
+	}
+	if opts.KeyMarker != "" {
+		params.Set("key-marker", s3.prefix(opts.KeyMarker))
+		if opts.VersionIDMarker != "" {
+			params.Set("version-id-marker", opts.VersionIDMarker)
+		}
+	}
+	if opts.MaxKeys > 0 {
+		params.Set("max-keys", strconv.Itoa(opts.MaxKeys))
+	}
+	return &VersionIterator{s3: s3, params: params}
+}
+
+// Next advances to the next version and reports whether there is one. It
+// returns false at the end of the listing or on error.
+func (it *VersionIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.fetch()
+	}
+	it.version, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Version returns the current version
+func (it *VersionIterator) Version() ObjectVersion {
+	return it.version
+}
+
+// Err returns the error that ended the iteration, if any
+func (it *VersionIterator) Err() error {
+	return it.err
+}
+
+// Markers returns the key and version markers to resume the listing after the
+// current page. They are empty on the last page.
+func (it *VersionIterator) Markers() (key, versionID string) {
+	return it.s3.relKey(it.params.Get("key-marker")), it.params.Get("version-id-marker")
+}
+
+func (it *VersionIterator) fetch() {
+	res, err := it.s3.listVersions(it.params)
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	it.done = !res.IsTruncated
+	if it.done {
+		it.params.Del("key-marker")
+		it.params.Del("version-id-marker")
+	} else {
+		it.params.Set("key-marker", res.NextKeyMarker)
+		it.params.Set("version-id-marker", res.NextVersionIdMarker)
+	}
+	it.page = res.versions(it.s3)
+}
+
+// versionEntry is a Version or DeleteMarker element of a version listing
+type versionEntry struct {
+	XMLName      xml.Name
+	Key          string
+	VersionId    string
+	IsLatest     bool
+	LastModified time.Time
+	ETag         string
+	Size         int64
+	StorageClass StorageClass
+	Owner        *Owner
+}
+
+// versionsResult is a page of a ListObjectVersions response. Versions and
+// delete markers are collected into Entries in document order.
+type versionsResult struct {
+	IsTruncated         bool
+	NextKeyMarker       string
+	NextVersionIdMarker string
+	Entries             []versionEntry `xml:",any"`
+}
+
+// versions converts the entries, dropping the other unknown elements collected
+// by the any field.
+func (res *versionsResult) versions(s3 *S3) []ObjectVersion {
+	v := make([]ObjectVersion, 0, len(res.Entries))
+	for _, e := range res.Entries {
+		n := e.XMLName.Local
+		if n != "Version" && n != "DeleteMarker" {
+			continue
+		}
+		v = append(v, ObjectVersion{
+			Key:            s3.relKey(e.Key),
+			VersionID:      e.VersionId,
+			IsLatest:       e.IsLatest,
+			IsDeleteMarker: n == "DeleteMarker",
+			LastModified:   e.LastModified,
+			ETag:           trimETag(e.ETag),
+			Size:           e.Size,
+			StorageClass:   e.StorageClass,
+			Owner:          e.Owner,
+		})
+	}
+	return v
+}
+
+// listVersions requests a single page of a version listing
+func (s3 *S3) listVersions(params url.Values) (*versionsResult, error) {
+	req, err := http.NewRequest("GET", s3.bucketURL("?versions"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s3.doParams(req, params, 200, "error listing versions")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	res := new(versionsResult)
+	if err := xml.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// listAllVersions calls fn for every version and delete marker of the keys with
+// the prefix, which is relative to the bucket root.
+func (s3 *S3) listAllVersions(prefix string, fn func(v ObjectVersion) error) error {
+	root := *s3
+	root.Path = ""
+	it := root.ListObjectVersions(VersionListOptions{Prefix: prefix})
+	for it.Next() {
+		if err := fn(it.Version()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+func (o *object) Versions() ([]ObjectVersion, error) {
+	var versions []ObjectVersion
+	key := o.Key()
+	err := o.s3.listAllVersions(key, func(v ObjectVersion) error {
+		// the prefix also matches longer keys
+		if v.Key == key {
+			v.Key = o.s3.relKey(v.Key)
+			versions = append(versions, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (o *object) RestoreVersion(versionID string) error {
+	versions, err := o.Versions()
+	if err != nil {
+		return err
+	}
+
+	i := 0
+	for i < len(versions) && versions[i].VersionID != versionID {
+		i++
+	}
+	if i == len(versions) {
+		return errVersionNotFound
+	}
+	if versions[i].IsDeleteMarker {
+		return errRestoreDeleteMarker
+	}
+
+	newer := versions[:i]
+	for _, v := range newer {
+		if !v.IsDeleteMarker {
+			c := new(Copier)
+			_, err := c.Copy(o, o, CopyOptions{SourceVersionID: versionID})
+			return err
+		}
+	}
+
+	// only delete markers hide the version
+	for _, v := range newer {
+		if err := o.Delete(DeleteOptions{VersionID: v.VersionID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionQuery returns the query addressing a version of an object, or an empty
+// string for the latest version.
+func versionQuery(id string) string {
+	if id == "" {
+		return ""
+	}
+	return "?versionId=" + url.QueryEscape(id)
+}
@@ -0,0 +1,140 @@
+package s3
+
+import (
+	"errors"
+	"io/fs"
+	"sync"
+)
+
+const (
+	nConcurrentListings = 8
+
+	// walkLookahead is the number of sibling directories listed ahead of the
+	// one being walked
+	walkLookahead = 4
+)
+
+var errWalkDone = errors.New("s3: walk already returned")
+
+// WalkFunc is called by Walk for every directory and object. Directories are
+// common prefixes, their entries have IsPrefix set. Returning fs.SkipDir for a
+// directory skips its contents, for an object it skips the remaining entries of
+// its directory. Returning fs.SkipAll stops the walk.
+//
+// If listing a directory fails, fn is called a second time for it with the
+// error. Any other error returned by fn stops the walk and is returned by Walk.
+type WalkFunc func(key string, e ListEntry, err error) error
+
+// Walk walks the key hierarchy below prefix like filepath.WalkDir, using / as
+// the delimiter. Entries are visited in key order and keys are relative to
+// Path. While fn is called for the entries of a directory, the next few sub
+// directories are already listed concurrently. When fn ends the walk, listings
+// that haven't started are dropped and Walk waits for the ones in flight, so no
+// requests are left running.
+func (s3 *S3) Walk(prefix string, fn WalkFunc) error {
+	w := &walker{
+		s3:   s3,
+		fn:   fn,
+		sem:  make(chan struct{}, nConcurrentListings),
+		done: make(chan struct{}),
+	}
+	defer w.wg.Wait()
+	defer close(w.done)
+
+	root := dirPrefix(prefix)
+	err := fn(root, ListEntry{Key: root, IsPrefix: true}, nil)
+	if err == nil {
+		err = w.walk(root, w.list(root))
+	}
+	if err == fs.SkipDir || err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+type walker struct {
+	s3   *S3
+	fn   WalkFunc
+	sem  chan struct{}
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// dirListing is the pending listing of a directory
+type dirListing struct {
+	done    chan struct{}
+	entries []ListEntry
+	err     error
+}
+
+// list starts listing dir in the background, unless the walk returns before a
+// request slot is free.
+func (w *walker) list(dir string) *dirListing {
+	l := &dirListing{done: make(chan struct{})}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer close(l.done)
+		select {
+		case w.sem <- struct{}{}:
+		case <-w.done:
+			l.err = errWalkDone
+			return
+		}
+		defer func() { <-w.sem }()
+
+		select {
+		case <-w.done:
+			l.err = errWalkDone
+		default:
+			l.entries, l.err = w.s3.readDir(dir)
+		}
+	}()
+	return l
+}
+
+func (w *walker) walk(dir string, l *dirListing) error {
+	<-l.done
+	if l.err != nil {
+		return w.fn(dir, ListEntry{Key: dir, IsPrefix: true}, l.err)
+	}
+
+	// sub directories are listed in key order, at most walkLookahead ahead
+	var subdirs []string
+	for _, e := range l.entries {
+		if e.IsPrefix {
+			subdirs = append(subdirs, e.Key)
+		}
+	}
+	pending := make(map[string]*dirListing)
+	next := 0
+	prefetch := func() {
+		for ; next < len(subdirs) && len(pending) < walkLookahead; next++ {
+			pending[subdirs[next]] = w.list(subdirs[next])
+		}
+	}
+	prefetch()
+
+	for _, e := range l.entries {
+		err := w.fn(e.Key, e, nil)
+		if !e.IsPrefix {
+			if err == fs.SkipDir {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		sub := pending[e.Key]
+		delete(pending, e.Key)
+		prefetch()
+		if err == nil {
+			err = w.walk(e.Key, sub)
+		}
+		if err != nil && err != fs.SkipDir {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,13 +3,11 @@ package s3
 import (
 	"bytes"
 	"encoding/xml"
-	"fmt"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
-	"path/filepath"
 	"strconv"
-	"strings"
 	"sync"
 )
 
@@ -44,12 +42,10 @@ type writer struct {
 	closed   bool
 	aborted  bool
 	uploadId string
+	opts     WriteOptions
+	parts    []*part
 	err      error
 	errAbort error
-	xml      struct {
-		XMLName string `xml:"CompleteMultipartUpload"`
-		Part    []*part
-	}
 }
 
 type part struct {
@@ -60,11 +56,12 @@ type part struct {
 	ETag       string
 }
 
-func newWriter(o *object) *writer {
+func newWriter(o *object, opts WriteOptions) *writer {
 	return &writer{
-		o:   o,
-		buf: new(bytes.Buffer),
-		pc:  make(chan *part, nConcurrentUploads),
+		o:    o,
+		buf:  new(bytes.Buffer),
+		pc:   make(chan *part, nConcurrentUploads),
+		opts: opts,
 	}
 }
 
@@ -73,41 +70,12 @@ func (w *writer) prepare() error {
 	if w.prepared {
 		return nil
 	}
-	req, err := http.NewRequest("POST", w.o.url("?uploads"), nil)
-	if err != nil {
-		return err
-	}
-
-	// detect mime type
-	ext := filepath.Ext(w.o.key)
-	contentType := "application/octet-stream"
-	if v, ok := mimeTypes[ext]; ok {
-		contentType = v
-	}
-	req.Header.Set(`Content-Type`, contentType)
-
-	// sign and send
-	w.o.s3.signRequest(req)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if c := resp.StatusCode; c != 200 {
-		return newS3Error(resp, "could not create multipart upload: %d", c)
-	}
-
-	var result struct {
-		UploadId string
-	}
-	err = xml.NewDecoder(resp.Body).Decode(&result)
+	uploadId, err := initiateUpload(w.o, w.opts)
 	if err != nil {
 		return err
 	}
 
-	w.uploadId = result.UploadId
+	w.uploadId = uploadId
 	w.prepared = true
 
 	return nil
@@ -157,7 +125,7 @@ func (w *writer) flush() {
 		PartNumber: w.partNum,
 		buf:        b,
 	}
-	w.xml.Part = append(w.xml.Part, p)
+	w.parts = append(w.parts, p)
 	w.wg.Add(1)
 	w.pc <- p
 }
@@ -166,7 +134,7 @@ func (w *writer) uploadPartRetry(p *part) {
 	defer w.wg.Done()
 	var err error
 	for i := 0; i < nRetries; i++ {
-		err = w.uploadPart(p)
+		err = uploadPart(w.o, w.uploadId, p, w.opts.Encryption)
 		if err == nil {
 			break
 		}
@@ -176,34 +144,30 @@ func (w *writer) uploadPartRetry(p *part) {
 	}
 }
 
-func (w *writer) uploadPart(p *part) error {
+// uploadPart uploads the buffer of part p. SSE-C uploads need the key for every
+// part.
+func uploadPart(o *object, uploadId string, p *part, enc *Encryption) error {
 	buf := bytes.NewBuffer(p.buf)
 
 	var uv = make(url.Values)
 	uv.Set("partNumber", strconv.Itoa(p.PartNumber))
-	uv.Set("uploadId", w.uploadId)
+	uv.Set("uploadId", uploadId)
 
-	url := w.o.url(`?` + uv.Encode())
+	url := o.url(`?` + uv.Encode())
 	req, err := http.NewRequest("PUT", url, buf)
 	if err != nil {
 		return err
 	}
 	req.ContentLength = int64(buf.Len())
+	enc.setCustomerHeaders(req.Header, ssePrefix)
 
-	w.o.s3.signRequest(req)
-
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := o.s3.do(req, 200, "could not upload part")
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if c := resp.StatusCode; c != 200 {
-		return newS3Error(resp, "could not upload part: %d", c)
-	}
-
-	// trim outer space and quotes from etag
-	p.ETag = strings.Trim(resp.Header.Get("etag"), ` "`)
+	p.ETag = trimETag(resp.Header.Get("etag"))
 
 	return nil
 }
@@ -225,90 +189,127 @@ func (w *writer) close(abort bool) error {
 	if abort {
 		return w.abort()
 	}
-	return w.complete()
+
+	err := w.complete()
+	if errors.Is(err, ErrPreconditionFailed) || errors.Is(err, ErrConflict) {
+		// the upload can't be completed anymore, don't leave the parts behind
+		w.abort()
+	}
+	return err
 }
 
 func (w *writer) abort() error {
-	uv := make(url.Values)
-	uv.Set("uploadId", w.uploadId)
-	url := w.o.url("?" + uv.Encode())
+	return abortUpload(w.o, w.uploadId)
+}
 
-	req, err := http.NewRequest("DELETE", url, nil)
+func (w *writer) complete() error {
+	_, err := completeUpload(w.o, w.uploadId, w.parts, w.opts)
+	return err
+}
+
+func (w *writer) Close() error {
+	return w.close(false)
+}
+
+func (w *writer) Abort() error {
+	return w.close(true)
+}
+
+// initiateUpload creates a multipart upload for o and returns its id
+func initiateUpload(o *object, opts WriteOptions) (string, error) {
+	req, err := http.NewRequest("POST", o.url("?uploads"), nil)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	w.o.s3.signRequest(req)
+	// detect mime type
+	req.Header.Set(`Content-Type`, o.contentType())
+	opts.setHeaders(req.Header)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := o.s3.do(req, 200, "could not create multipart upload")
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer resp.Body.Close()
 
-	if c := resp.StatusCode; c != 204 {
-		return newS3Error(resp, "could not abort upload: %d", c)
+	var result struct {
+		UploadId string
 	}
-
-	return nil
-}
-
-func (w *writer) complete() error {
-
-	b, err := xml.Marshal(w.xml)
+	err = xml.NewDecoder(resp.Body).Decode(&result)
 	if err != nil {
-		return err
+		return "", err
 	}
 
+	return result.UploadId, nil
+}
+
+// abortUpload aborts a multipart upload and frees the uploaded parts
+func abortUpload(o *object, uploadId string) error {
 	uv := make(url.Values)
-	uv.Set("uploadId", w.uploadId)
+	uv.Set("uploadId", uploadId)
+	url := o.url("?" + uv.Encode())
 
-	url := w.o.url(`?` + uv.Encode())
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(b))
+	req, err := http.NewRequest("DELETE", url, nil)
 	if err != nil {
 		return err
 	}
 
-	w.o.s3.signRequest(req)
-
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := o.s3.do(req, 204, "could not abort upload")
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	resp.Body.Close()
 
-	if c := resp.StatusCode; c != 200 {
-		return newS3Error(resp, "could not complete upload: %d", c)
-	}
 	return nil
 }
 
-func (w *writer) Close() error {
-	return w.close(false)
-}
+// completeUpload assembles the parts into the object. The parts must be sorted
+// by part number.
+func completeUpload(o *object, uploadId string, parts []*part, opts WriteOptions) (*CopyResult, error) {
+	var v struct {
+		XMLName string `xml:"CompleteMultipartUpload"`
+		Part    []*part
+	}
+	v.Part = parts
 
-func (w *writer) Abort() error {
-	return w.close(true)
-}
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
 
-type s3err struct {
-	code    int
-	text    string
-	xmlBody string
-}
+	uv := make(url.Values)
+	uv.Set("uploadId", uploadId)
 
-func newS3Error(resp *http.Response, strFmt string, args ...interface{}) *s3err {
-	var b bytes.Buffer
-	if resp != nil {
-		b.ReadFrom(resp.Body)
+	url := o.url(`?` + uv.Encode())
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(b))
+	if err != nil {
+		return nil, err
+	}
+	opts.setConditions(req.Header)
+
+	resp, err := o.s3.do(req, 200, "could not complete upload")
+	if err != nil {
+		return nil, err
 	}
-	return &s3err{
-		code:    resp.StatusCode,
-		text:    fmt.Sprintf(strFmt, args...),
-		xmlBody: b.String(),
+	defer resp.Body.Close()
+
+	// the upload may still fail after the 200 status was sent
+	res, err := readResult(resp, "could not complete upload")
+	if err != nil {
+		return nil, err
 	}
+	res.VersionID = resp.Header.Get("x-amz-version-id")
+	return res, nil
 }
 
-func (e *s3err) Error() string {
-	return e.text
+// finishUpload completes a multipart upload. If that fails for any reason, the
+// upload is aborted so its parts aren't left behind, and the error of
+// completing is returned.
+func finishUpload(o *object, uploadId string, parts []*part, opts WriteOptions) (*CopyResult, error) {
+	res, err := completeUpload(o, uploadId, parts, opts)
+	if err != nil {
+		abortUpload(o, uploadId)
+		return nil, err
+	}
+	return res, nil
 }